@@ -0,0 +1,101 @@
+package assign
+
+import "math"
+
+//Hungarian コスト行列を受け取り, コストの和が最小になる割り当てをハンガリアン法で求め, 各行に割り当てられた列を返す
+//（行の数が列の数より多い場合, 割り当てられなかった行は-1になる）
+func Hungarian(cost [][]float64) []int {
+	n := len(cost)
+	if n == 0 {
+		return []int{}
+	}
+	m := len(cost[0])
+	//行の数が列の数以下になるように, コスト0の列を補う
+	if m < n {
+		padded := make([][]float64, n)
+		for i := range cost {
+			padded[i] = make([]float64, n)
+			copy(padded[i], cost[i])
+		}
+		ret := Hungarian(padded)
+		for i := range ret {
+			if ret[i] >= m {
+				ret[i] = -1
+			}
+		}
+		return ret
+	}
+	//ポテンシャルを使うO(n^2 m)の実装（添字は1から始まり, 0は番兵）
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1) //各列に割り当てられた行
+	way := make([]int, m+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		//増加路に沿って割り当てを更新する
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+	ret := make([]int, n)
+	for i := range ret {
+		ret[i] = -1
+	}
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			ret[p[j]-1] = j - 1
+		}
+	}
+	return ret
+}
+
+//TotalCost コスト行列と割り当てを受け取り, コストの和を返す
+func TotalCost(cost [][]float64, assignment []int) float64 {
+	total := 0.0
+	for i, j := range assignment {
+		if j >= 0 {
+			total += cost[i][j]
+		}
+	}
+	return total
+}
@@ -0,0 +1,65 @@
+package assign
+
+import (
+	"math/rand"
+	"testing"
+)
+
+//bruteForce 全ての割り当てを試して最小のコストを返す
+func bruteForce(cost [][]float64) float64 {
+	n, m := len(cost), len(cost[0])
+	best := -1.0
+	used := make([]bool, m)
+	var dfs func(i int, sum float64)
+	dfs = func(i int, sum float64) {
+		if i == n {
+			if best < 0 || sum < best {
+				best = sum
+			}
+			return
+		}
+		for j := 0; j < m; j++ {
+			if !used[j] {
+				used[j] = true
+				dfs(i+1, sum+cost[i][j])
+				used[j] = false
+			}
+		}
+	}
+	dfs(0, 0)
+	return best
+}
+
+func TestHungarian(t *testing.T) {
+	cost := [][]float64{
+		{4, 1, 3},
+		{2, 0, 5},
+		{3, 2, 2},
+	}
+	assignment := Hungarian(cost)
+	if TotalCost(cost, assignment) != 5 {
+		t.Fatalf("total cost should be `5`, but `%v` (%v)", TotalCost(cost, assignment), assignment)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for k := 0; k < 50; k++ {
+		n, m := 1+rnd.Intn(4), 1+rnd.Intn(5)
+		cost := make([][]float64, n)
+		for i := range cost {
+			cost[i] = make([]float64, m)
+			for j := range cost[i] {
+				cost[i][j] = float64(rnd.Intn(20))
+			}
+		}
+		assignment := Hungarian(cost)
+		seen := make(map[int]bool)
+		for _, j := range assignment {
+			if j >= 0 && seen[j] {
+				t.Fatalf("column %v is assigned twice (%v)", j, assignment)
+			}
+			seen[j] = true
+		}
+		if n <= m && TotalCost(cost, assignment) != bruteForce(cost) {
+			t.Fatalf("total cost should be `%v`, but `%v` (%v)", bruteForce(cost), TotalCost(cost, assignment), cost)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package auction
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/greedy"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Auction 逐次単一アイテムオークションによるアイテムの割り当て
+//（新しいアイテムが出現するたびに, まだ拾われていない全てのアイテムを競売にかけ直す）
+type Auction struct {
+	env      *env.Env
+	Bundles  [][]int //各エージェントが落札したアイテムの番号（拾う順）
+	Auctions int     //競売を行った回数
+	Changes  int     //競売のやり直しで落札者が変わったアイテムの数
+
+	status []agentStatus //前回の競売のときの各エージェントの状態
+}
+
+//agentStatus 入札に影響するエージェントの状態
+type agentStatus struct {
+	Broken   bool //故障して動けない
+	Charging bool //充電ステーションに向かっている（入札しない）
+}
+
+//New 環境設定を受け取り, まだ何も割り当てていないAuctionを返す
+func New(env *env.Env) *Auction {
+	return &Auction{env: env, Bundles: make([][]int, env.NumAgents)}
+}
+
+//Clone 割り当てを複製したAuctionを返す
+func (a *Auction) Clone() *Auction {
+	bundles := make([][]int, len(a.Bundles))
+	for id, bundle := range a.Bundles {
+		bundles[id] = append([]int{}, bundle...)
+	}
+	return &Auction{env: a.env, Bundles: bundles, Auctions: a.Auctions, Changes: a.Changes, status: append([]agentStatus{}, a.status...)}
+}
+
+//plan あるエージェントが落札したアイテムを拾い終える見込みのターン数, 座標, 持っているアイテム
+type plan struct {
+	T       int
+	Pos     pos.Pos
+	Carried []item.Item
+}
+
+//Update 現在の状態と直前に出現したアイテムの座標を受け取り, 割り当てを更新する
+//（拾われたり消滅したりしたアイテムは落札したエージェントの予定から取り除く.
+//新しいアイテムが出現したときに加えて, 故障したり充電ステーションに向かったりしたエージェントがいるときも競売をやり直す）
+func (a *Auction) Update(s *state.State, appeared []pos.Pos) {
+	floor := make(map[int]pos.Pos)
+	for p, items := range s.PosItems {
+		for _, it := range items {
+			floor[it.ID] = p
+		}
+	}
+	winner := make(map[int]int)
+	for id, bundle := range a.Bundles {
+		rest := bundle[:0]
+		for _, itemID := range bundle {
+			if _, exist := floor[itemID]; exist {
+				rest = append(rest, itemID)
+				winner[itemID] = id
+			}
+		}
+		a.Bundles[id] = rest
+	}
+	status := a.currentStatus(s)
+	changed := false
+	for id := range status {
+		if a.status == nil || status[id] != a.status[id] {
+			changed = true
+		}
+	}
+	if len(appeared) == 0 && !changed && a.Auctions > 0 {
+		return
+	}
+	a.status = status
+	a.Auctions++
+	a.Bundles = a.run(s, floor)
+	for id, bundle := range a.Bundles {
+		for _, itemID := range bundle {
+			if prev, exist := winner[itemID]; exist && prev != id {
+				a.Changes++
+			}
+		}
+	}
+}
+
+//currentStatus 各エージェントの入札に影響する状態を返す
+func (a *Auction) currentStatus(s *state.State) []agentStatus {
+	status := make([]agentStatus, a.env.NumAgents)
+	for id := range status {
+		status[id].Broken = s.AgentRepair != nil && s.AgentRepair[id] > 0
+		_, status[id].Charging = greedy.NeedCharge(id, s, a.env)
+	}
+	return status
+}
+
+//run 床の全てのアイテムを競売にかけ, 各エージェントが落札したアイテムを返す
+//（各ラウンドで, 全てのエージェントが全ての残りのアイテムに入札し, 最も安い入札が落札される）
+func (a *Auction) run(s *state.State, floor map[int]pos.Pos) [][]int {
+	env := a.env
+	bundles := make([][]int, env.NumAgents)
+	plans := make([]plan, env.NumAgents)
+	bidding := make([]bool, env.NumAgents)
+	for id := range plans {
+		plans[id] = plan{Pos: s.AgentPos[id], Carried: s.AgentItems[id]}
+		if s.AgentRepair != nil {
+			plans[id].T = s.AgentRepair[id]
+		}
+		//充電ステーションに向かうエージェントは入札しない
+		_, charge := greedy.NeedCharge(id, s, env)
+		bidding[id] = !charge
+	}
+	//番号の順に並べて, 同じ入札額なら番号が小さいアイテムとエージェントを優先する
+	itemIDs := make([]int, 0, len(floor))
+	for itemID := range floor {
+		itemIDs = append(itemIDs, itemID)
+	}
+	sort.Ints(itemIDs)
+	floorItems := make(map[int]item.Item)
+	for _, items := range s.PosItems {
+		for _, it := range items {
+			floorItems[it.ID] = it
+		}
+	}
+	sold := make(map[int]bool)
+	for len(sold) < len(itemIDs) {
+		bestAgent, bestItem := -1, -1
+		var bestPlan plan
+		for id := range plans {
+			if !bidding[id] {
+				continue
+			}
+			for _, itemID := range itemIDs {
+				if sold[itemID] {
+					continue
+				}
+				nxt, ok := a.bid(id, plans[id], floor[itemID], floorItems[itemID], s.Turn)
+				if ok && (bestAgent == -1 || nxt.T < bestPlan.T) {
+					bestAgent, bestItem, bestPlan = id, itemID, nxt
+				}
+			}
+		}
+		//誰も入札できるアイテムがない
+		if bestAgent == -1 {
+			break
+		}
+		sold[bestItem] = true
+		bundles[bestAgent] = append(bundles[bestAgent], bestItem)
+		plans[bestAgent] = bestPlan
+	}
+	return bundles
+}
+
+//bid あるエージェントがこれまでの予定の後にあるアイテムを拾うときの予定を返す（入札できなければfalse）
+//（入札額は拾い終えるまでのターン数で, 持てるアイテムがいっぱいなら先に持っているアイテムを受け付ける最も近いデポに寄る）
+func (a *Auction) bid(id int, cur plan, target pos.Pos, it item.Item, turn int) (plan, bool) {
+	env := a.env
+	if len(cur.Carried) >= env.Capacity(id) {
+		depot, ok := a.acceptingDepot(id, cur.Pos, cur.Carried)
+		if !ok {
+			return plan{}, false
+		}
+		d, _ := env.Dist(id, cur.Pos, depot)
+		//デポが受け付けないアイテムは持ったまま
+		rest := make([]item.Item, 0, len(cur.Carried))
+		for _, carried := range cur.Carried {
+			if !env.Accepts(depot, carried.Type) {
+				rest = append(rest, carried)
+			}
+		}
+		cur = plan{T: cur.T + d + 1, Pos: depot, Carried: rest}
+	}
+	d, reachable := env.Dist(id, cur.Pos, target)
+	if !reachable {
+		return plan{}, false
+	}
+	t := cur.T + d + 1
+	//たどり着く前に消滅してしまうなら入札しない
+	if env.ItemLifetime > 0 && turn+t-1-it.SpawnTurn >= env.ItemLifetime {
+		return plan{}, false
+	}
+	carried := append(append(make([]item.Item, 0, len(cur.Carried)+1), cur.Carried...), it)
+	return plan{T: t, Pos: target, Carried: carried}, true
+}
+
+//Targets 各エージェントが次に向かうべき座標を返す（向かう先がなければfalse）
+//（持てるアイテムがいっぱいか, 落札したアイテムがなくアイテムを持っているなら受け付け可能なデポに, そうでなければ落札した最初のアイテムに向かう）
+func (a *Auction) Targets(s *state.State) ([]pos.Pos, []bool) {
+	env := a.env
+	targets := make([]pos.Pos, env.NumAgents)
+	ok := make([]bool, env.NumAgents)
+	floor := make(map[int]pos.Pos)
+	for p, items := range s.PosItems {
+		for _, it := range items {
+			floor[it.ID] = p
+		}
+	}
+	for id := range targets {
+		carried := s.AgentItems[id]
+		if len(carried) > 0 && (len(carried) >= env.Capacity(id) || len(a.Bundles[id]) == 0) {
+			targets[id], ok[id] = a.acceptingDepot(id, s.AgentPos[id], carried)
+			continue
+		}
+		if len(a.Bundles[id]) > 0 {
+			targets[id], ok[id] = floor[a.Bundles[id][0]]
+		}
+	}
+	return targets, ok
+}
+
+//acceptingDepot 持っているアイテムを受け付けるデポのうち, 最も近いものの座標を返す（なければfalse）
+func (a *Auction) acceptingDepot(id int, p pos.Pos, carried []item.Item) (pos.Pos, bool) {
+	var nearest pos.Pos
+	best := -1
+	for _, depot := range a.env.Depots {
+		if a.env.NumAccepted(depot.Pos, carried) == 0 {
+			continue
+		}
+		if d, reachable := a.env.Dist(id, p, depot.Pos); reachable && (best == -1 || d < best) {
+			nearest = depot.Pos
+			best = d
+		}
+	}
+	return nearest, best != -1
+}
+
+//Actions 割り当てに従って全てのエージェントの行動を決定する
+func (a *Auction) Actions(s *state.State, rnd *rand.Rand, check bool) ([]int, []float64) {
+	targets, ok := a.Targets(s)
+	return greedy.Pursue(targets, ok, s, a.env, rnd, check)
+}
@@ -0,0 +1,67 @@
+package auction
+
+import (
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+func TestUpdate(t *testing.T) {
+	e, err := env.Load("../env/testdata/typed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentPos := []pos.Pos{pos.New(0, 0), pos.New(6, 3)}
+	posItems := map[pos.Pos][]item.Item{
+		pos.New(1, 3): {item.New(0, 0, 1, 0)},
+		pos.New(6, 4): {item.New(1, 0, 1, 0)},
+	}
+	s := state.New(1, make([][]item.Item, 2), agentPos, posItems, nil, make([]bool, 2))
+	a := New(e)
+	a.Update(s, nil)
+	if len(a.Bundles[0]) != 1 || a.Bundles[0][0] != 0 || len(a.Bundles[1]) != 1 || a.Bundles[1][0] != 1 {
+		t.Fatalf("bundles should be `[[0] [1]]`, but `%v`", a.Bundles)
+	}
+	targets, ok := a.Targets(s)
+	if !ok[0] || targets[0] != pos.New(1, 3) {
+		t.Fatalf("target of agent 0 should be `(1, 3)`, but `%v`", targets[0])
+	}
+	//エージェント1が拾ったアイテムは割り当てから取り除かれ, 持っているアイテムをデポに運ぶ
+	nxt := state.New(2, [][]item.Item{{}, {item.New(1, 0, 1, 0)}}, agentPos, map[pos.Pos][]item.Item{pos.New(1, 3): {item.New(0, 0, 1, 0)}}, nil, make([]bool, 2))
+	a.Update(nxt, nil)
+	if a.Auctions != 1 || len(a.Bundles[1]) != 0 {
+		t.Fatalf("item 1 should be removed without a new auction, but `%v` (%v auctions)", a.Bundles, a.Auctions)
+	}
+	targets, ok = a.Targets(nxt)
+	if !ok[1] || targets[1] != pos.New(6, 6) {
+		t.Fatalf("target of agent 1 should be the depot `(6, 6)`, but `%v`", targets[1])
+	}
+	//エージェント0が故障すれば, 新しいアイテムが出現していなくても競売をやり直す
+	nxt.Turn = 3
+	nxt.AgentRepair = []int{5, 0}
+	a.Update(nxt, nil)
+	if a.Auctions != 2 {
+		t.Fatalf("breakdown should start a new auction, but %v auctions", a.Auctions)
+	}
+	a.Update(nxt, nil)
+	if a.Auctions != 2 {
+		t.Fatalf("unchanged status should not start a new auction, but %v auctions", a.Auctions)
+	}
+}
+
+func TestBidDetour(t *testing.T) {
+	e, err := env.Load("../env/testdata/depots.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(e)
+	//(6, 3)のデポは種類0のアイテムを受け付けないので, いっぱいのエージェントは(0, 3)のデポに寄ってから拾いに行く
+	cur := plan{Pos: pos.New(6, 3), Carried: []item.Item{item.New(0, 0, 1, 0), item.New(1, 0, 1, 0)}}
+	nxt, ok := a.bid(0, cur, pos.New(5, 3), item.New(2, 1, 1, 0), 1)
+	if !ok || nxt.T != 13 || len(nxt.Carried) != 1 {
+		t.Fatalf("bid should take 13 turns with 1 item carried, but `%+v` (%v)", nxt, ok)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/gym"
+)
+
+//serveGym gymサブコマンド: reset, stepを受け付けるサーバーを起動する
+func serveGym(args []string) {
+	flags := flag.NewFlagSet("gym", flag.ExitOnError)
+	envPath := flags.String("env", "", "環境設定ファイルのパス")
+	network := flags.String("network", "tcp", "待ち受けるソケットの種類（tcp, unix）")
+	addr := flags.String("addr", "127.0.0.1:5555", "待ち受けるアドレス（unixならソケットファイルのパス）")
+
+	flags.Parse(args)
+	env, err := env.Load(*envPath)
+	if err != nil {
+		panic(err)
+	}
+	l, err := net.Listen(*network, *addr)
+	if err != nil {
+		panic(err)
+	}
+	defer l.Close()
+	fmt.Printf("listening on %v %v\n", *network, l.Addr())
+	if err := gym.NewServer(env).Serve(l); err != nil {
+		panic(err)
+	}
+}
@@ -4,19 +4,42 @@ import (
 	"flag"
 	"fmt"
 	"math/rand"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/Div9851/warehouse-sim/comm"
 	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/heatmap"
+	"github.com/Div9851/warehouse-sim/mapf"
+	"github.com/Div9851/warehouse-sim/remote"
 	"github.com/Div9851/warehouse-sim/sim"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "train":
+			train(os.Args[2:])
+			return
+		case "gym":
+			serveGym(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "play":
+			play(os.Args[2:])
+			return
+		}
+	}
 	envPath := flag.String("env", "", "環境設定ファイルのパス")
 	concurrent := flag.Int("concurrent", 1, "並行して実行するシミュレーションの数")
 	total := flag.Int("total", 1, "実行するシミュレーションの数")
 	verbose := flag.Bool("verbose", false, "シミュレーションの詳細を出力するかどうか")
 	seed := flag.Int64("seed", -1, "乱数のシード値")
+	heatmapDir := flag.String("heatmap", "", "マスごとのカウンタ（CSV, PNG, SVG）を書き出すディレクトリ")
 
 	flag.Parse()
 	env, err := env.Load(*envPath)
@@ -27,6 +50,27 @@ func main() {
 	var totalItems int
 	var totalPickupCounts int
 	var totalClearCounts int
+	var totalLate int
+	var totalExpired int
+	var totalOnFloor int
+	var totalThroughput float64
+	var totalKnownRatio float64
+	var pickupLatency float64
+	var pickups int
+	var maxPickupLatency int
+	deliveryLatencies := []int{}
+	var agentStats sim.AgentStats
+	var blockedMoves int
+	var vertexConflicts int
+	var swapConflicts int
+	var deadlocks int
+	var maxWaitStreak int
+	var commStats comm.Stats
+	var auctions int
+	var reassignments int
+	var planning mapf.Stats
+	var remoteStats remote.Stats
+	hm := heatmap.New(env.MapDataH, env.MapDataW)
 
 	if *seed != -1 {
 		rand.Seed(*seed)
@@ -43,7 +87,10 @@ func main() {
 		for i := 0; i < now; i++ {
 			wg.Add(1)
 			go func(idx int) {
-				sim := sim.New(env, rand.Int63())
+				sim, err := sim.New(env, rand.Int63())
+				if err != nil {
+					panic(err)
+				}
 				sim.Do(*verbose)
 				results[idx] = sim.GetResult()
 				wg.Done()
@@ -58,6 +105,68 @@ func main() {
 			for _, clear := range result.ClearCounts {
 				totalClearCounts += clear
 			}
+			totalLate += result.LateItems
+			totalExpired += result.ExpiredItems
+			totalOnFloor += result.ItemsOnFloor
+			totalThroughput += result.Throughput
+			totalKnownRatio += result.KnownItemRatio
+			for _, rec := range result.Items {
+				if rec.PickupTurn > 0 {
+					pickupLatency += float64(rec.PickupTurn - rec.SpawnTurn)
+					pickups++
+				}
+			}
+			if result.MaxPickupLatency > maxPickupLatency {
+				maxPickupLatency = result.MaxPickupLatency
+			}
+			deliveryLatencies = append(deliveryLatencies, result.DeliveryLatencies...)
+			for _, stats := range result.AgentStats {
+				agentStats.Moving += stats.Moving
+				agentStats.Blocked += stats.Blocked
+				agentStats.Working += stats.Working
+				agentStats.Idle += stats.Idle
+				agentStats.Charging += stats.Charging
+				agentStats.Dead += stats.Dead
+				agentStats.Broken += stats.Broken
+				agentStats.Slips += stats.Slips
+				agentStats.FailedPickups += stats.FailedPickups
+				agentStats.Breakdowns += stats.Breakdowns
+				agentStats.Carrying += stats.Carrying
+			}
+			c := result.Congestion
+			for id := range c.BlockedMoves {
+				blockedMoves += c.BlockedMoves[id]
+				vertexConflicts += c.VertexConflicts[id]
+				swapConflicts += c.SwapConflicts[id]
+				if c.MaxWaitStreak[id] > maxWaitStreak {
+					maxWaitStreak = c.MaxWaitStreak[id]
+				}
+			}
+			deadlocks += c.NumDeadlocks
+			commStats.Sent += result.Comm.Sent
+			commStats.Delivered += result.Comm.Delivered
+			commStats.Dropped += result.Comm.Dropped
+			commStats.OutRange += result.Comm.OutRange
+			commStats.Deferred += result.Comm.Deferred
+			commStats.Superseded += result.Comm.Superseded
+			auctions += result.Auctions
+			reassignments += result.Reassignments
+			planning.Replans += result.Planning.Replans
+			planning.Failures += result.Planning.Failures
+			planning.Periodic += result.Planning.Periodic
+			planning.OnItem += result.Planning.OnItem
+			planning.OnGoal += result.Planning.OnGoal
+			planning.OnFailure += result.Planning.OnFailure
+			planning.Executed += result.Planning.Executed
+			planning.ChangedAgents += result.Planning.ChangedAgents
+			planning.ChangedSteps += result.Planning.ChangedSteps
+			remoteStats.Requests += result.Remote.Requests
+			remoteStats.Timeouts += result.Remote.Timeouts
+			remoteStats.Errors += result.Remote.Errors
+			remoteStats.Fallbacks += result.Remote.Fallbacks
+			if err := hm.Add(result.Heatmap); err != nil {
+				panic(err)
+			}
 		}
 		endTime := time.Now()
 		processTime := endTime.Sub(startTime).Seconds()
@@ -68,4 +177,67 @@ func main() {
 	fmt.Printf("avg. items: %v\n", float64(totalItems)/float64(*total))
 	fmt.Printf("avg. pickup: %v\n", float64(totalPickupCounts)/float64(*total))
 	fmt.Printf("avg. clear: %v\n", float64(totalClearCounts)/float64(*total))
+	fmt.Printf("avg. late: %v\n", float64(totalLate)/float64(*total))
+	fmt.Printf("avg. expired: %v\n", float64(totalExpired)/float64(*total))
+	fmt.Printf("avg. left on floor: %v\n", float64(totalOnFloor)/float64(*total))
+	fmt.Printf("avg. throughput (per 100 turns): %v\n", totalThroughput/float64(*total))
+	if env.IsPartiallyObservable() {
+		fmt.Printf("avg. known item ratio: %.3f\n", totalKnownRatio/float64(*total))
+	}
+	if pickups > 0 {
+		fmt.Printf("avg. pickup latency: %v (max %v)\n", pickupLatency/float64(pickups), maxPickupLatency)
+	}
+	if len(deliveryLatencies) > 0 {
+		sort.Ints(deliveryLatencies)
+		sum := 0
+		for _, l := range deliveryLatencies {
+			sum += l
+		}
+		n := len(deliveryLatencies)
+		fmt.Printf("avg. wait: %v\n", float64(sum)/float64(n))
+		fmt.Printf("wait p50/p90/p99/max: %v/%v/%v/%v\n", sim.Percentile(deliveryLatencies, 0.5), sim.Percentile(deliveryLatencies, 0.9),
+			sim.Percentile(deliveryLatencies, 0.99), sim.Percentile(deliveryLatencies, 1))
+	}
+	fmt.Printf("avg. blocked moves: %v\n", float64(blockedMoves)/float64(*total))
+	fmt.Printf("avg. vertex/swap conflicts: %v/%v\n", float64(vertexConflicts)/float64(*total), float64(swapConflicts)/float64(*total))
+	fmt.Printf("avg. deadlocks: %v (max wait streak %v)\n", float64(deadlocks)/float64(*total), maxWaitStreak)
+	if env.UsesComm() {
+		fmt.Printf("avg. messages sent/delivered/dropped/out of range/deferred/superseded: %v/%v/%v/%v/%v/%v\n", float64(commStats.Sent)/float64(*total),
+			float64(commStats.Delivered)/float64(*total), float64(commStats.Dropped)/float64(*total), float64(commStats.OutRange)/float64(*total),
+			float64(commStats.Deferred)/float64(*total), float64(commStats.Superseded)/float64(*total))
+	}
+	if env.UsesAlgorithm("AUCTION") {
+		fmt.Printf("avg. auctions/reassignments: %v/%v\n", float64(auctions)/float64(*total), float64(reassignments)/float64(*total))
+	}
+	if env.UsesMAPF() {
+		fmt.Printf("avg. replans/failures: %v/%v\n", float64(planning.Replans)/float64(*total), float64(planning.Failures)/float64(*total))
+		fmt.Printf("avg. replans periodic/new item/new goal/failure: %v/%v/%v/%v\n", float64(planning.Periodic)/float64(*total),
+			float64(planning.OnItem)/float64(*total), float64(planning.OnGoal)/float64(*total), float64(planning.OnFailure)/float64(*total))
+		//最初の計画は計画し直した回数に含めない
+		if replans := planning.Replans - *total; replans > 0 {
+			fmt.Printf("plan stability: %.2f steps executed, %.2f agents/%.2f steps changed per replan\n", float64(planning.Executed)/float64(replans),
+				float64(planning.ChangedAgents)/float64(replans), float64(planning.ChangedSteps)/float64(replans))
+		}
+	}
+	if env.UsesAlgorithm("REMOTE") {
+		fmt.Printf("avg. remote requests/timeouts/errors/fallbacks: %v/%v/%v/%v\n", float64(remoteStats.Requests)/float64(*total),
+			float64(remoteStats.Timeouts)/float64(*total), float64(remoteStats.Errors)/float64(*total), float64(remoteStats.Fallbacks)/float64(*total))
+	}
+	if env.UsesNoise() {
+		fmt.Printf("avg. slips/failed pickups/breakdowns: %v/%v/%v\n", float64(agentStats.Slips)/float64(*total),
+			float64(agentStats.FailedPickups)/float64(*total), float64(agentStats.Breakdowns)/float64(*total))
+	}
+	agentTurns := float64(agentStats.Moving + agentStats.Blocked + agentStats.Working + agentStats.Idle + agentStats.Charging + agentStats.Dead + agentStats.Broken)
+	if agentTurns > 0 {
+		fmt.Printf("utilisation moving/blocked/working/idle/charging/dead/broken/carrying: %.3f/%.3f/%.3f/%.3f/%.3f/%.3f/%.3f/%.3f\n",
+			float64(agentStats.Moving)/agentTurns, float64(agentStats.Blocked)/agentTurns, float64(agentStats.Working)/agentTurns,
+			float64(agentStats.Idle)/agentTurns, float64(agentStats.Charging)/agentTurns, float64(agentStats.Dead)/agentTurns,
+			float64(agentStats.Broken)/agentTurns, float64(agentStats.Carrying)/agentTurns)
+	}
+	if *heatmapDir != "" {
+		if err := hm.Export(*heatmapDir, env.MapData); err != nil {
+			panic(err)
+		}
+		fmt.Printf("heatmaps written to %v\n", *heatmapDir)
+	}
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/sim"
+	"github.com/Div9851/warehouse-sim/tui"
+)
+
+//play playサブコマンド: HUMANのエージェントを端末からキーボードで操作する
+func play(args []string) {
+	flags := flag.NewFlagSet("play", flag.ExitOnError)
+	envPath := flags.String("env", "", "環境設定ファイルのパス")
+	seed := flags.Int64("seed", 0, "乱数のシード値")
+	history := flags.Int("history", 1000, "undoで戻せるターン数の上限")
+
+	flags.Parse(args)
+	env, err := env.Load(*envPath)
+	if err != nil {
+		panic(err)
+	}
+	s, err := sim.New(env, *seed)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+	s.KeepHistory = *history
+	//1文字ずつ読めるように端末を非カノニカルモードにし, 終了時に元に戻す
+	saved, err := stty("-g")
+	if err != nil {
+		panic(err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		panic(err)
+	}
+	err = tui.Run(s, os.Stdin, os.Stdout)
+	stty(strings.TrimSpace(saved))
+	if err != nil {
+		panic(err)
+	}
+	result := s.GetResult()
+	fmt.Printf("\nturn %v: delivered %v items, throughput %v\n", s.State.Turn, result.DeliveredItems, result.Throughput)
+}
+
+//stty 端末の設定を変更し, 出力を返す
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("can't run stty (%s)", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/web"
+)
+
+//serve serveサブコマンド: シミュレーションを実行し, ブラウザで見られるようにする
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	envPath := flags.String("env", "", "環境設定ファイルのパス")
+	addr := flags.String("addr", "127.0.0.1:8080", "待ち受けるアドレス")
+	seed := flags.Int64("seed", 0, "最初のシミュレーションのシード値")
+	delay := flags.Int("delay", 200, "1ターンあたりの待ち時間（ミリ秒）")
+
+	flags.Parse(args)
+	env, err := env.Load(*envPath)
+	if err != nil {
+		panic(err)
+	}
+	server, err := web.NewServer(env, *seed, time.Duration(*delay)*time.Millisecond)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("open http://%v/\n", *addr)
+	if err := http.ListenAndServe(*addr, server.Handler()); err != nil {
+		panic(err)
+	}
+}
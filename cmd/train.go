@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/learn"
+	"github.com/Div9851/warehouse-sim/sim"
+)
+
+//train trainサブコマンド: 各エージェントの行動価値の表を学習してファイルに書き出す
+//（学習済みの方策は生成物なのでリポジトリには含めず, LEARNEDを使う環境設定も置いていない. 例えば
+//go run ./cmd train -env _experiment/warehouse-small/greedy.json -seed 1 -out _experiment/warehouse-small/learned_policy.json
+//で方策を書き出し, greedy.jsonのalgorithmsをLEARNEDにして"learned_policy": "learned_policy.json"を加えた設定で実行する）
+func train(args []string) {
+	flags := flag.NewFlagSet("train", flag.ExitOnError)
+	envPath := flags.String("env", "", "環境設定ファイルのパス")
+	episodes := flags.Int("episodes", 5000, "学習するエピソードの数")
+	method := flags.String("method", "Q", "学習の方法（Q, SARSA）")
+	alpha := flags.Float64("alpha", 0.1, "学習率")
+	gamma := flags.Float64("gamma", 0.95, "割引率")
+	epsilon := flags.Float64("epsilon", 1.0, "ε-greedyでランダムに行動する確率の初期値")
+	epsilonMin := flags.Float64("epsilon_min", 0.05, "εの下限")
+	epsilonDecay := flags.Float64("epsilon_decay", 0.999, "1エピソードごとにεに掛ける値")
+	out := flags.String("out", "", "学習した方策を書き出すファイルのパス（空なら環境設定のlearned_policy）")
+	seed := flags.Int64("seed", 0, "乱数のシード値")
+	report := flags.Int("report", 500, "何エピソードごとに平均の報酬を出力するか")
+
+	flags.Parse(args)
+	if *episodes <= 0 || *report <= 0 {
+		panic(fmt.Errorf("-episodes and -report must be positive"))
+	}
+	env, err := env.Load(*envPath)
+	if err != nil {
+		panic(err)
+	}
+	cfg := learn.Config{Method: *method, Alpha: *alpha, Gamma: *gamma, Epsilon: *epsilon, EpsilonMin: *epsilonMin, EpsilonDecay: *epsilonDecay}
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	if *out == "" {
+		*out = env.PolicyPath
+	}
+	if *out == "" {
+		panic(fmt.Errorf("neither -out nor learned_policy is given"))
+	}
+	//初期状態だけを使うので, 学習中の方策をまだ読み込まないようにアルゴリズムを外しておく
+	initEnv := *env
+	initEnv.Algorithms = nil
+	rnd := rand.New(rand.NewSource(*seed))
+	learner := learn.NewLearner(env, cfg, rnd)
+	sum := 0.0
+	for episode := 1; episode <= *episodes; episode++ {
+		s, err := sim.New(&initEnv, rnd.Int63())
+		if err != nil {
+			panic(err)
+		}
+		sum += learner.Episode(s.State)
+		if episode%*report == 0 || episode == *episodes {
+			n := episode % *report
+			if n == 0 {
+				n = *report
+			}
+			fmt.Printf("episode %v: avg. reward %.2f, epsilon %.3f, states %v\n", episode, sum/float64(n), learner.Epsilon, len(learner.Policy.Tables[0]))
+			sum = 0
+		}
+	}
+	if err := learner.Policy.Save(*out); err != nil {
+		panic(err)
+	}
+	fmt.Printf("policy written to %v\n", *out)
+}
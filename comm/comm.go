@@ -0,0 +1,153 @@
+package comm
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//Message エージェントが他のエージェントに送る意図
+type Message struct {
+	From     int
+	SentTurn int
+	HasClaim bool      //Claimが有効なら真
+	Claim    pos.Pos   //向かっているアイテムまたはデポの座標
+	Path     []pos.Pos //SentTurnの次のターンから順にいる予定の座標
+}
+
+//NextPos 送り主が現在いる座標を受け取り, 経路上でその次にいる予定の座標を返す
+//（移動に失敗して予定より遅れていても経路をたどり直す. 現在の座標が経路上になければfalse）
+func (msg Message) NextPos(now pos.Pos) (pos.Pos, bool) {
+	for k, p := range msg.Path {
+		if p == now && k+1 < len(msg.Path) {
+			return msg.Path[k+1], true
+		}
+	}
+	//経路の最初の座標への移動に失敗していたら, もう一度移動しようとする
+	if len(msg.Path) > 0 && pos.Abs(now.X-msg.Path[0].X)+pos.Abs(now.Y-msg.Path[0].Y) == 1 {
+		return msg.Path[0], true
+	}
+	return pos.Pos{}, false
+}
+
+//envelope 配送待ちのメッセージ
+type envelope struct {
+	Msg         Message
+	To          int
+	DeliverTurn int
+}
+
+//Stats 通信の集計
+type Stats struct {
+	Sent       int `json:"sent"`       //送られたメッセージの数（宛先ごとに数える）
+	Delivered  int `json:"delivered"`  //届いたメッセージの数
+	Dropped    int `json:"dropped"`    //途中で失われたメッセージの数
+	OutRange   int `json:"out_range"`  //宛先が遠すぎて届かなかったメッセージの数
+	Deferred   int `json:"deferred"`   //帯域が足りず次のターンに回されたメッセージの数（延べ数）
+	Superseded int `json:"superseded"` //帯域が足りずに届かないうちに同じ送り主からの新しいメッセージに置き換えられたメッセージの数
+}
+
+//Channel 遅延, 欠落, 到達距離, 帯域のある通信路
+type Channel struct {
+	env     *env.Env
+	rnd     *rand.Rand
+	pending []envelope
+	inboxes []map[int]Message //各エージェントが各送り主から受け取った最新のメッセージ
+	Stats   Stats
+}
+
+//New 環境設定と乱数生成器を受け取り, 空の通信路を返す
+func New(env *env.Env, rnd *rand.Rand) *Channel {
+	inboxes := make([]map[int]Message, env.NumAgents)
+	for i := range inboxes {
+		inboxes[i] = make(map[int]Message)
+	}
+	return &Channel{env: env, rnd: rnd, inboxes: inboxes}
+}
+
+//Clone 配送待ちのメッセージと受信箱を複製した通信路を返す（乱数生成器は共有する）
+func (ch *Channel) Clone() *Channel {
+	inboxes := make([]map[int]Message, len(ch.inboxes))
+	for i, inbox := range ch.inboxes {
+		inboxes[i] = make(map[int]Message, len(inbox))
+		for from, msg := range inbox {
+			inboxes[i][from] = msg
+		}
+	}
+	return &Channel{env: ch.env, rnd: ch.rnd, pending: append([]envelope{}, ch.pending...), inboxes: inboxes, Stats: ch.Stats}
+}
+
+//Broadcast 送り主の位置から届く範囲にいる全てのエージェントにメッセージを送る
+func (ch *Channel) Broadcast(msg Message, agentPos []pos.Pos) {
+	from := agentPos[msg.From]
+	for to, p := range agentPos {
+		if to == msg.From {
+			continue
+		}
+		ch.Stats.Sent++
+		if r := ch.env.Comm.Range; r > 0 && pos.Abs(from.X-p.X)+pos.Abs(from.Y-p.Y) > r {
+			ch.Stats.OutRange++
+			continue
+		}
+		if ch.env.Comm.DropProb > 0 && ch.rnd.Float64() < ch.env.Comm.DropProb {
+			ch.Stats.Dropped++
+			continue
+		}
+		e := envelope{Msg: msg, To: to, DeliverTurn: msg.SentTurn + ch.env.Comm.Delay}
+		//帯域が足りずに届いていない同じ宛先への古いメッセージは, 新しいメッセージに置き換える
+		replaced := false
+		for k := range ch.pending {
+			if ch.pending[k].To == to && ch.pending[k].Msg.From == msg.From && ch.pending[k].DeliverTurn <= msg.SentTurn {
+				ch.pending[k] = e
+				ch.Stats.Superseded++
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			ch.pending = append(ch.pending, e)
+		}
+	}
+}
+
+//Deliver あるターンまでに届くメッセージを宛先の受信箱に入れる（帯域を超えた分は次のターンに回す）
+func (ch *Channel) Deliver(turn int) {
+	//先に送られたものから届ける
+	sort.SliceStable(ch.pending, func(i, j int) bool {
+		return ch.pending[i].DeliverTurn < ch.pending[j].DeliverTurn
+	})
+	rest := ch.pending[:0]
+	delivered := 0
+	for _, e := range ch.pending {
+		if e.DeliverTurn > turn {
+			rest = append(rest, e)
+			continue
+		}
+		if bw := ch.env.Comm.Bandwidth; bw > 0 && delivered >= bw {
+			ch.Stats.Deferred++
+			rest = append(rest, e)
+			continue
+		}
+		delivered++
+		ch.Stats.Delivered++
+		//古いメッセージで新しいメッセージを上書きしない
+		if old, exist := ch.inboxes[e.To][e.Msg.From]; !exist || old.SentTurn <= e.Msg.SentTurn {
+			ch.inboxes[e.To][e.Msg.From] = e.Msg
+		}
+	}
+	ch.pending = rest
+}
+
+//Inbox あるエージェントが各送り主から受け取った最新のメッセージを送り主の順に返す
+func (ch *Channel) Inbox(id int) []Message {
+	msgs := make([]Message, 0, len(ch.inboxes[id]))
+	for _, msg := range ch.inboxes[id] {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].From < msgs[j].From
+	})
+	return msgs
+}
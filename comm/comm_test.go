@@ -0,0 +1,46 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+func TestChannel(t *testing.T) {
+	e := &env.Env{NumAgents: 3, Comm: env.Comm{Delay: 2, Range: 5, Bandwidth: 1}}
+	ch := New(e, rand.New(rand.NewSource(1)))
+	agentPos := []pos.Pos{pos.New(0, 0), pos.New(3, 0), pos.New(10, 0)}
+	ch.Broadcast(Message{From: 0, SentTurn: 1, HasClaim: true, Claim: pos.New(2, 0)}, agentPos)
+	ch.Broadcast(Message{From: 1, SentTurn: 1}, agentPos)
+	if ch.Stats.Sent != 4 || ch.Stats.OutRange != 2 {
+		t.Fatalf("sent/out of range should be `4/2`, but `%v/%v`", ch.Stats.Sent, ch.Stats.OutRange)
+	}
+	ch.Deliver(2)
+	if len(ch.Inbox(1)) != 0 {
+		t.Fatal("no message should arrive before the delay")
+	}
+	//帯域が1なので1ターンに1つしか届かない
+	ch.Deliver(3)
+	if len(ch.Inbox(1)) != 1 || len(ch.Inbox(0)) != 0 {
+		t.Fatalf("only agent 1 should receive a message at turn 3, but `%v` `%v`", ch.Inbox(0), ch.Inbox(1))
+	}
+	ch.Deliver(4)
+	if len(ch.Inbox(0)) != 1 || ch.Stats.Deferred != 1 {
+		t.Fatalf("agent 0 should receive the deferred message at turn 4, but `%v` (deferred `%v`)", ch.Inbox(0), ch.Stats.Deferred)
+	}
+}
+
+func TestNextPos(t *testing.T) {
+	msg := Message{From: 0, SentTurn: 1, Path: []pos.Pos{pos.New(1, 0), pos.New(2, 0), pos.New(2, 1)}}
+	if p, ok := msg.NextPos(pos.New(2, 0)); !ok || p != pos.New(2, 1) {
+		t.Fatalf("next pos from (2, 0) should be `(2, 1)`, but `%v`", p)
+	}
+	if p, ok := msg.NextPos(pos.New(0, 0)); !ok || p != pos.New(1, 0) {
+		t.Fatalf("next pos from (0, 0) should be `(1, 0)`, but `%v`", p)
+	}
+	if _, ok := msg.NextPos(pos.New(5, 5)); ok {
+		t.Fatal("next pos from (5, 5) should be unknown")
+	}
+}
@@ -0,0 +1,77 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//アイテムの到着モデル
+const (
+	ArrivalBernoulli = "BERNOULLI" //1ターンに確率AppearProbで1つ出現する（既定）
+	ArrivalPoisson   = "POISSON"   //1ターンに平均Rateのポアソン分布に従う数だけ出現する
+	ArrivalBatch     = "BATCH"     //1ターンに確率AppearProbでBatchMin〜BatchMax個が同じ座標にまとめて出現する
+)
+
+//Arrival アイテムの到着過程の設定
+type Arrival struct {
+	Model       string    `json:"model"`
+	Rate        float64   `json:"rate"`
+	BatchMin    int       `json:"batch_min"`
+	BatchMax    int       `json:"batch_max"`
+	Curve       []float64 `json:"curve"`        //時間帯ごとの需要の倍率（1周期を等分して順に使う）
+	CurvePeriod int       `json:"curve_period"` //Curveの1周期のターン数（0ならlast_turn）
+	BurstProb   float64   `json:"burst_prob"`   //各ターンにバーストが始まる確率
+	BurstTurns  int       `json:"burst_turns"`  //バーストが続くターン数
+	BurstFactor float64   `json:"burst_factor"` //バースト中の到着率の倍率
+	HotSpots    []HotSpot `json:"hot_spots"`
+}
+
+//HotSpot ある座標のアイテムの出現の重みを上書きする設定
+type HotSpot struct {
+	Pos    pos.Pos `json:"pos"`
+	Weight int     `json:"weight"`
+}
+
+//setupArrival 到着過程の設定を検証し, 省略された値を補う
+func setupArrival(env *Env) error {
+	arrival := &env.Arrival
+	switch arrival.Model {
+	case "":
+		arrival.Model = ArrivalBernoulli
+	case ArrivalBernoulli, ArrivalPoisson:
+	case ArrivalBatch:
+		if arrival.BatchMin <= 0 {
+			arrival.BatchMin = 1
+		}
+		if arrival.BatchMax < arrival.BatchMin {
+			arrival.BatchMax = arrival.BatchMin
+		}
+	default:
+		return fmt.Errorf("unknown arrival model `%s`", arrival.Model)
+	}
+	if len(arrival.Curve) > 0 && arrival.CurvePeriod <= 0 {
+		arrival.CurvePeriod = env.LastTurn
+		if arrival.CurvePeriod <= 0 {
+			arrival.CurvePeriod = len(arrival.Curve)
+		}
+	}
+	if arrival.BurstProb > 0 && (arrival.BurstTurns <= 0 || arrival.BurstFactor <= 0) {
+		return fmt.Errorf("burst_turns and burst_factor must be positive when burst_prob is set")
+	}
+	for _, spot := range arrival.HotSpots {
+		p := spot.Pos
+		if 0 > p.X || p.X >= env.MapDataW || 0 > p.Y || p.Y >= env.MapDataH {
+			return fmt.Errorf("hot spot (%v, %v) is not on a floor cell", p.X, p.Y)
+		}
+		//アイテムが出現するのは床と初期位置のマスだけなので, それ以外の重みを上書きしても無視されてしまう
+		if t := env.Cells[p.Y][p.X].Type; (t != CellFloor && t != CellStart) || env.IsDepot(p) {
+			return fmt.Errorf("hot spot (%v, %v) is not on a cell where items can appear", p.X, p.Y)
+		}
+		if spot.Weight < 0 {
+			return fmt.Errorf("hot spot (%v, %v) has negative weight", p.X, p.Y)
+		}
+		env.Cells[p.Y][p.X].Weight = spot.Weight
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//Battery エージェントの電池の設定（Capacityが0なら電池切れしない）
+type Battery struct {
+	Capacity   int `json:"capacity"`
+	MoveCost   int `json:"move_cost"`   //1マス移動するときに消費する量
+	ActionCost int `json:"action_cost"` //アイテムを拾う/回収するときに消費する量
+	ChargeRate int `json:"charge_rate"` //充電ステーションでSTAYしたときに1ターンで充電される量
+	Reserve    int `json:"reserve"`     //貪欲法で充電ステーションまでの移動に必要な量に加えて残しておく量（0ならCapacityの1/10）
+}
+
+//setupBattery 電池の設定を検証し, 省略された値を補う
+func setupBattery(env *Env) error {
+	battery := &env.Battery
+	if battery.Capacity <= 0 {
+		return nil
+	}
+	if len(env.ChargerPos) == 0 {
+		return fmt.Errorf("battery is enabled but the map has no charging station")
+	}
+	if battery.ChargeRate <= 0 {
+		return fmt.Errorf("charge_rate must be positive when battery is enabled")
+	}
+	if battery.Reserve <= 0 {
+		battery.Reserve = battery.Capacity / 10
+	}
+	return nil
+}
+
+//UsesBattery 電池の設定が有効かどうかを返す
+func (env *Env) UsesBattery() bool {
+	return env.Battery.Capacity > 0
+}
+
+//IsCharger ある座標が充電ステーションかどうかを返す
+func (env *Env) IsCharger(p pos.Pos) bool {
+	return env.CellAt(p).Type == CellCharger
+}
+
+//NearestCharger あるエージェントにとって, ある座標から最も近い使用中でない充電ステーションの座標と距離を返す
+//（進入できる充電ステーションにたどり着けなければfalse）
+func (env *Env) NearestCharger(id int, p pos.Pos, occupied map[pos.Pos]bool) (pos.Pos, int, bool) {
+	var nearest pos.Pos
+	best := -1
+	for _, c := range env.ChargerPos {
+		if occupied[c] || !env.CanEnter(id, c) {
+			continue
+		}
+		if d, reachable := env.Dist(id, p, c); reachable && (best == -1 || d < best) {
+			nearest = c
+			best = d
+		}
+	}
+	return nearest, best, best != -1
+}
@@ -0,0 +1,155 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//マップデータの凡例
+//
+//	#       壁
+//	.       通路（アイテムの出現の重みは1）
+//	0-9     通路（アイテムの出現の重みをその数字にする. 0ならアイテムは出現しない）
+//	D       デポ（全ての種類のアイテムを受け付ける. 種類を制限するときはdepotsで同じ座標を指定する）
+//	S       エージェントの初期位置の候補
+//	^ v < > 一方通行の通路（記号の向きと逆向きには進めない）
+//	C       充電ステーション（アイテムは出現しない）
+//	X       停止禁止の通路（通過はできるが, アイテムは出現せず初期位置にもならない）
+//	        （停止禁止を守るのは経路を計画するMAPFのソルバーとWHCAの予約表だけで,
+//	        GREEDYやMCTSなど他のアルゴリズムのエージェントはこのマスにとどまることがある）
+
+//マスの種類
+const (
+	CellFloor int = iota
+	CellWall
+	CellDepot
+	CellStart
+	CellCharger
+	CellNoStop
+)
+
+//Cell マップの1マスを表す構造体
+type Cell struct {
+	Type   int
+	Dir    int //一方通行の向き（action.UP, action.DOWN, action.LEFT, action.RIGHT. 一方通行でなければaction.STAY）
+	Weight int //アイテムの出現の重み
+}
+
+//CellAt ある座標のマスの情報を返す
+func (env *Env) CellAt(p pos.Pos) Cell {
+	return env.Cells[p.Y][p.X]
+}
+
+//IsNoStop ある座標が停止禁止のマスかどうかを返す
+func (env *Env) IsNoStop(p pos.Pos) bool {
+	return env.CellAt(p).Type == CellNoStop
+}
+
+//parseCell マップデータの1文字を受け取り, Cellを返す
+func parseCell(c rune) (Cell, error) {
+	switch {
+	case c == '#':
+		return Cell{Type: CellWall, Dir: action.STAY}, nil
+	case c == '.':
+		return Cell{Type: CellFloor, Dir: action.STAY, Weight: 1}, nil
+	case '0' <= c && c <= '9':
+		return Cell{Type: CellFloor, Dir: action.STAY, Weight: int(c - '0')}, nil
+	case c == 'D':
+		return Cell{Type: CellDepot, Dir: action.STAY}, nil
+	case c == 'S':
+		return Cell{Type: CellStart, Dir: action.STAY, Weight: 1}, nil
+	case c == '^':
+		return Cell{Type: CellFloor, Dir: action.UP, Weight: 1}, nil
+	case c == 'v':
+		return Cell{Type: CellFloor, Dir: action.DOWN, Weight: 1}, nil
+	case c == '<':
+		return Cell{Type: CellFloor, Dir: action.LEFT, Weight: 1}, nil
+	case c == '>':
+		return Cell{Type: CellFloor, Dir: action.RIGHT, Weight: 1}, nil
+	case c == 'C':
+		return Cell{Type: CellCharger, Dir: action.STAY}, nil
+	case c == 'X':
+		return Cell{Type: CellNoStop, Dir: action.STAY}, nil
+	}
+	return Cell{}, fmt.Errorf("unknown map symbol `%c`", c)
+}
+
+//parseMapData マップデータを受け取り, 各マスの情報のグリッドを返す
+func parseMapData(mapData []string) ([][]Cell, error) {
+	if len(mapData) == 0 {
+		return nil, fmt.Errorf("empty map data")
+	}
+	W := len(mapData[0])
+	cells := make([][]Cell, len(mapData))
+	for y, row := range mapData {
+		if len(row) != W {
+			return nil, fmt.Errorf("map data row %v has width %v (expected %v)", y, len(row), W)
+		}
+		cells[y] = make([]Cell, 0, W)
+		for x, c := range row {
+			cell, err := parseCell(c)
+			if err != nil {
+				return nil, fmt.Errorf("(%v, %v): %s", x, y, err)
+			}
+			cells[y] = append(cells[y], cell)
+		}
+	}
+	return cells, nil
+}
+
+//normalizeMapData 移動の判定に必要な記号（壁, 一方通行, 充電ステーション）以外を通路に置き換えたマップデータを返す
+func normalizeMapData(mapData []string) []string {
+	normalized := make([]string, len(mapData))
+	for y, row := range mapData {
+		b := []byte(row)
+		for x, c := range b {
+			switch c {
+			case '#', '^', 'v', '<', '>', 'C':
+			default:
+				b[x] = '.'
+			}
+		}
+		normalized[y] = string(b)
+	}
+	return normalized
+}
+
+//findCells 各マスの情報のグリッドを受け取り, ある種類のマスの座標のスライスを返す
+func findCells(cells [][]Cell, cellType int) []pos.Pos {
+	found := []pos.Pos{}
+	for y, row := range cells {
+		for x, cell := range row {
+			if cell.Type == cellType {
+				found = append(found, pos.New(x, y))
+			}
+		}
+	}
+	return found
+}
+
+//getSpawnPos 各マスの情報のグリッドとデポ以外の通路の座標を受け取り,
+//アイテムが出現し得る座標のスライスと重みの累積和（全ての重みが等しい場合はnil）を返す
+func getSpawnPos(cells [][]Cell, allPos []pos.Pos) ([]pos.Pos, []int) {
+	spawnPos := []pos.Pos{}
+	cumWeights := []int{}
+	uniform := true
+	sum := 0
+	for _, p := range allPos {
+		w := cells[p.Y][p.X].Weight
+		if w == 0 {
+			continue
+		}
+		if len(spawnPos) > 0 && w != cells[spawnPos[0].Y][spawnPos[0].X].Weight {
+			uniform = false
+		}
+		sum += w
+		spawnPos = append(spawnPos, p)
+		cumWeights = append(cumWeights, sum)
+	}
+	if uniform {
+		return spawnPos, nil
+	}
+	return spawnPos, cumWeights
+}
@@ -0,0 +1,31 @@
+package env
+
+import "fmt"
+
+//Comm エージェント間の通信の設定（GREEDY_COMMのエージェントが使う）
+type Comm struct {
+	Delay     int     `json:"delay"`     //送ってから届くまでのターン数（0なら1）
+	DropProb  float64 `json:"drop_prob"` //メッセージが届かない確率
+	Range     int     `json:"range"`     //メッセージが届くマンハッタン距離（0なら制限なし）
+	Bandwidth int     `json:"bandwidth"` //1ターンに届けられるメッセージの数（超えた分は次のターンに回す. 0なら制限なし）
+}
+
+//setupComm 通信の設定を検証し, 省略された値を補う
+func setupComm(env *Env) error {
+	comm := &env.Comm
+	if comm.DropProb < 0 || comm.DropProb > 1 {
+		return fmt.Errorf("drop_prob %v is out of [0, 1]", comm.DropProb)
+	}
+	if comm.Delay < 0 || comm.Range < 0 || comm.Bandwidth < 0 {
+		return fmt.Errorf("delay, range and bandwidth must not be negative")
+	}
+	if comm.Delay == 0 {
+		comm.Delay = 1
+	}
+	return nil
+}
+
+//UsesComm エージェント間の通信を使うアルゴリズムがあるかどうかを返す
+func (env *Env) UsesComm() bool {
+	return env.UsesAlgorithm("GREEDY_COMM")
+}
@@ -9,9 +9,16 @@ import (
 	"path/filepath"
 
 	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/item"
 	"github.com/Div9851/warehouse-sim/pos"
 )
 
+//Depot デポの設定
+type Depot struct {
+	Pos       pos.Pos `json:"pos"`
+	ItemTypes []int   `json:"item_types"` //受け付けるアイテムの種類（空なら全ての種類を受け付ける）
+}
+
 //Env 環境設定
 type Env struct {
 	NumAgents   int      `json:"num_agents"`
@@ -21,9 +28,32 @@ type Env struct {
 	DIYBonus    float64  `json:"DIY_bonus"` //自分でアイテムを運んだとき/回収したときに追加で得られるReward
 	MapDataPath string   `json:"map_data_path"`
 	AppearProb  float64  `json:"appear_prob"`
-	DepotPos    pos.Pos  `json:"depot_pos"`
-	Algorithms  []string `json:"algorithms"` //GREEDY, MCTS, MCTS_OPT
+	Arrival     Arrival  `json:"arrival"`
+	TracePath   string   `json:"arrival_trace_path"`
+	DepotPos    pos.Pos  `json:"depot_pos"` //depotsもマップデータのデポもない場合に使う単一のデポ
+	Depots      []Depot  `json:"depots"`
+	ItemTypes   int      `json:"item_types"` //アイテムの種類の数（0なら1種類）
+	Algorithms  []string `json:"algorithms"` //GREEDY, GREEDY_COMM, HUNGARIAN, AUCTION, CBS, ECBS, PBS, PP, LEARNED, REMOTE, HUMAN, MCTS, MCTS_OPT
 	GreedyCA    bool     `json:"greedy_ca"`
+	PathPlanner string   `json:"path_planner"` //greedy_caでの衝突回避の方法: ONE_STEP（空の場合も）, WHCA
+	WHCAWindow  int      `json:"whca_window"`  //WHCAで予約表を使って経路を計画するターン数（0なら8）
+
+	ItemDeadline  int     `json:"item_deadline"`  //出現から何ターン以内にデポに運ぶ必要があるか（0なら期限なし）
+	ItemLifetime  int     `json:"item_lifetime"`  //拾われないまま何ターン経つと消滅するか（0なら消滅しない）
+	LatePenalty   float64 `json:"late_penalty"`   //期限を過ぎて運んだときにアイテム1つあたりに差し引かれるReward
+	ExpiryPenalty float64 `json:"expiry_penalty"` //アイテムが消滅したときに差し引かれるReward
+	UrgencyCoef   float64 `json:"urgency_coef"`   //貪欲法で期限の近いアイテムを優先する度合い
+	DeadlockTurns int     `json:"deadlock_turns"` //何ターン連続して互いに移動できなければデッドロックとみなすか（0なら5）
+	Battery       Battery `json:"battery"`
+	Noise         Noise   `json:"noise"`
+	Sensing       Sensing `json:"sensing"`
+	Comm          Comm    `json:"comm"`
+	MAPF          MAPF    `json:"mapf"`
+	Remote        Remote  `json:"remote"`
+
+	LearnedPolicy string `json:"learned_policy"` //LEARNEDのエージェントが使う学習済みの方策のファイルのパス（環境設定ファイルからの相対パス）
+
+	AgentProfiles []Profile `json:"agent_profiles"` //各エージェントの性能（空なら全てのエージェントが同じ性能）
 
 	DiscountFactor float64 `json:"mcts_discount_factor"`
 	ExpandTheresh  int     `json:"mcts_expand_thresh"` //ノードを展開する閾値
@@ -31,41 +61,183 @@ type Env struct {
 	MaxDepth       int     `json:"mcts_max_depth"`
 	NumOfIter      int     `json:"mcts_num_of_iter"`
 	UCTparam       float64 `json:"uct_param"`
+	RolloutPolicy  string  `json:"mcts_rollout_policy"` //GREEDY（空の場合も）, HUNGARIAN
 	MapData        []string
 	MapDataH       int
 	MapDataW       int
+	Cells          [][]Cell
 	MinDist        map[pos.Pos]map[pos.Pos]int
 	AllPos         []pos.Pos         //壁でない全ての座標のスライス（デポを含まない）
+	StartPos       []pos.Pos         //エージェントの初期位置の候補（マップデータにSがなければ空）
+	SpawnPos       []pos.Pos         //アイテムが出現し得る座標のスライス
+	CumWeights     []int             //SpawnPosの各座標の出現の重みの累積和（全て等しい場合はnil）
+	ChargerPos     []pos.Pos         //充電ステーションの座標のスライス
+	DepotIndex     map[pos.Pos]int   //デポの座標からDepotsの添字へのマップ
 	ValidMoves     map[pos.Pos][]int //その場所で選択できる行動のリスト
+	Trace          map[int][]pos.Pos //ターンからそのターンにアイテムが出現する座標へのマップ（TracePathがなければnil）
+	PolicyPath     string            //LearnedPolicyを環境設定ファイルのディレクトリから解決したパス（LEARNEDのエージェントがいなければ空）
+
+	AgentValidMoves []map[pos.Pos][]int           //各エージェントが各場所で選択できる行動のリスト（進入できるマスが制限されていなければnil）
+	AgentMinDist    []map[pos.Pos]map[pos.Pos]int //各エージェントにとっての最短距離（進入できるマスが制限されていなければnil）
 }
 
 //Load 環境設定をJSONファイルから読み込む
+//（MapDataは壁, 一方通行, 充電ステーション以外の記号を通路に置き換えたもの, Cellsは各マスの情報のグリッド）
 func Load(path string) (*Env, error) {
 	env, err := loadFromJSON(path)
 	if err != nil {
 		return nil, err
 	}
 	dir := filepath.Dir(path)
-	env.MapData, err = loadMapData(filepath.Join(dir, env.MapDataPath))
+	mapData, err := loadMapData(filepath.Join(dir, env.MapDataPath))
 	if err != nil {
 		return nil, err
 	}
+	env.Cells, err = parseMapData(mapData)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse `%s` (%s)", env.MapDataPath, err)
+	}
+	env.MapData = normalizeMapData(mapData)
 	env.MapDataH = len(env.MapData)
 	env.MapDataW = len(env.MapData[0])
-	env.AllPos = getAllPos(env.MapData, env.DepotPos)
-	env.ValidMoves = make(map[pos.Pos][]int)
-	for _, p := range env.AllPos {
-		env.ValidMoves[p] = getValidMoves(env.MapData, p)
+	env.DepotIndex = make(map[pos.Pos]int)
+	for i, depot := range env.Depots {
+		if _, exist := env.DepotIndex[depot.Pos]; exist {
+			return nil, fmt.Errorf("duplicate depot (%v, %v)", depot.Pos.X, depot.Pos.Y)
+		}
+		env.DepotIndex[depot.Pos] = i
+	}
+	//マップデータのデポのうち, depotsで指定されていないものは全ての種類を受け付ける
+	for _, p := range findCells(env.Cells, CellDepot) {
+		if _, exist := env.DepotIndex[p]; !exist {
+			env.DepotIndex[p] = len(env.Depots)
+			env.Depots = append(env.Depots, Depot{Pos: p})
+		}
+	}
+	if len(env.Depots) == 0 {
+		env.DepotIndex[env.DepotPos] = 0
+		env.Depots = []Depot{{Pos: env.DepotPos}}
+	}
+	env.DepotPos = env.Depots[0].Pos
+	depotPos := make([]pos.Pos, len(env.Depots))
+	for i, depot := range env.Depots {
+		p := depot.Pos
+		if 0 > p.X || p.X >= env.MapDataW || 0 > p.Y || p.Y >= env.MapDataH || env.Cells[p.Y][p.X].Type == CellWall {
+			return nil, fmt.Errorf("depot (%v, %v) is not on a floor cell", p.X, p.Y)
+		}
+		depotPos[i] = p
+	}
+	env.AllPos = getAllPos(env.MapData, depotPos...)
+	env.StartPos = findCells(env.Cells, CellStart)
+	if len(env.StartPos) > 0 && len(env.StartPos) < env.NumAgents {
+		return nil, fmt.Errorf("%v start cells for %v agents", len(env.StartPos), env.NumAgents)
+	}
+	env.ChargerPos = findCells(env.Cells, CellCharger)
+	if env.DeadlockTurns <= 0 {
+		env.DeadlockTurns = 5
+	}
+	if err := setupBattery(env); err != nil {
+		return nil, err
+	}
+	if err := setupPlanner(env); err != nil {
+		return nil, err
+	}
+	if err := setupMAPF(env); err != nil {
+		return nil, err
+	}
+	if env.UsesAlgorithm("LEARNED") {
+		if env.LearnedPolicy == "" {
+			return nil, fmt.Errorf("learned_policy is required for LEARNED agents")
+		}
+		env.PolicyPath = filepath.Join(dir, env.LearnedPolicy)
+	}
+	if err := setupRemote(env, dir); err != nil {
+		return nil, err
+	}
+	if err := setupComm(env); err != nil {
+		return nil, err
+	}
+	if err := setupSensing(env); err != nil {
+		return nil, err
+	}
+	if err := setupNoise(env); err != nil {
+		return nil, err
 	}
-	env.ValidMoves[env.DepotPos] = getValidMoves(env.MapData, env.DepotPos)
+	if err := setupArrival(env); err != nil {
+		return nil, err
+	}
+	env.SpawnPos, env.CumWeights = getSpawnPos(env.Cells, env.AllPos)
+	if env.TracePath != "" {
+		env.Trace, err = loadTrace(filepath.Join(dir, env.TracePath))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTrace(env); err != nil {
+			return nil, err
+		}
+	}
+	if len(env.SpawnPos) == 0 && env.TracePath == "" && (env.AppearProb > 0 || env.Arrival.Rate > 0) {
+		return nil, fmt.Errorf("no cell where items can appear")
+	}
+	env.ValidMoves = make(map[pos.Pos][]int)
 	env.MinDist = make(map[pos.Pos]map[pos.Pos]int)
-	for _, p := range env.AllPos {
+	for _, p := range append(depotPos, env.AllPos...) {
+		env.ValidMoves[p] = getValidMoves(env.MapData, p)
 		env.MinDist[p] = doBFS(env.MapData, p)
 	}
-	env.MinDist[env.DepotPos] = doBFS(env.MapData, env.DepotPos)
+	if err := setupProfiles(env); err != nil {
+		return nil, err
+	}
+	if err := setupStarts(env); err != nil {
+		return nil, err
+	}
 	return env, nil
 }
 
+//UsesAlgorithm あるアルゴリズムを使うエージェントがいるかどうかを返す
+func (env *Env) UsesAlgorithm(name string) bool {
+	for _, algo := range env.Algorithms {
+		if algo == name {
+			return true
+		}
+	}
+	return false
+}
+
+//IsDepot ある座標がデポかどうかを返す
+func (env *Env) IsDepot(p pos.Pos) bool {
+	_, exist := env.DepotIndex[p]
+	return exist
+}
+
+//Accepts ある座標のデポがある種類のアイテムを受け付けるかどうかを返す
+func (env *Env) Accepts(p pos.Pos, itemType int) bool {
+	idx, exist := env.DepotIndex[p]
+	if !exist {
+		return false
+	}
+	if len(env.Depots[idx].ItemTypes) == 0 {
+		return true
+	}
+	for _, t := range env.Depots[idx].ItemTypes {
+		if t == itemType {
+			return true
+		}
+	}
+	return false
+}
+
+//NumAccepted ある座標のデポが受け付けるアイテムの数を返す
+func (env *Env) NumAccepted(p pos.Pos, items []item.Item) int {
+	cnt := 0
+	for _, it := range items {
+		if env.Accepts(p, it.Type) {
+			cnt++
+		}
+	}
+	return cnt
+}
+
 //loadFromJSON 環境設定をJSONファイルから読み込む
 func loadFromJSON(path string) (*Env, error) {
 	f, err := os.Open(path)
@@ -107,11 +279,15 @@ func loadMapData(path string) ([]string, error) {
 }
 
 //getAllPos マップデータとデポの座標を受け取り, 壁でない全ての座標のスライスを返す（デポを含まない）
-func getAllPos(mapData []string, depotPos pos.Pos) []pos.Pos {
+func getAllPos(mapData []string, depotPos ...pos.Pos) []pos.Pos {
+	isDepot := make(map[pos.Pos]bool)
+	for _, p := range depotPos {
+		isDepot[p] = true
+	}
 	allPos := []pos.Pos{}
 	for y, row := range mapData {
 		for x, col := range row {
-			if col != '#' && (x != depotPos.X || y != depotPos.Y) {
+			if col != '#' && !isDepot[pos.New(x, y)] {
 				allPos = append(allPos, pos.New(x, y))
 			}
 		}
@@ -134,23 +310,26 @@ func getValidMoves(mapData []string, targetPos pos.Pos) []int {
 }
 
 //doBFS マップデータと始点を受け取り, 各点までの最短距離のマップを返す
+//（一方通行があるので始点から各点への距離であり, 逆向きの距離とは一致しないことがある）
 func doBFS(mapData []string, startPos pos.Pos) map[pos.Pos]int {
-	H := len(mapData)
-	W := len(mapData[0])
+	return doBFSIn(mapData, startPos, nil)
+}
+
+//doBFSIn 進入できるマスを制限したdoBFS（canEnterがnilなら制限しない）
+func doBFSIn(mapData []string, startPos pos.Pos, canEnter func(pos.Pos) bool) map[pos.Pos]int {
+	moves := []int{action.UP, action.DOWN, action.LEFT, action.RIGHT}
 	minDist := make(map[pos.Pos]int)
-	dx := []int{1, 0, -1, 0}
-	dy := []int{0, 1, 0, -1}
 	que := []pos.Pos{startPos}
 	minDist[startPos] = 0
 	for len(que) > 0 {
 		now := que[0]
 		que = que[1:]
-		for i := 0; i < 4; i++ {
-			nx := now.X + dx[i]
-			ny := now.Y + dy[i]
-			nxt := pos.New(nx, ny)
-			_, visited := minDist[nxt]
-			if 0 > nx || nx >= W || 0 > ny || ny >= H || mapData[ny][nx] == '#' || visited {
+		for _, move := range moves {
+			nxt := pos.NextPos(now, move, mapData)
+			if _, visited := minDist[nxt]; visited {
+				continue
+			}
+			if canEnter != nil && !canEnter(nxt) {
 				continue
 			}
 			que = append(que, nxt)
@@ -3,6 +3,8 @@ package env
 import (
 	"testing"
 
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/item"
 	"github.com/Div9851/warehouse-sim/pos"
 )
 
@@ -162,3 +164,293 @@ func TestLoad(t *testing.T) {
 		t.Fatalf("env.MinDist[(6, 6)][(0, 3)] should be `9`, but `%v`", env.MinDist[from][to])
 	}
 }
+
+func TestLoadDepots(t *testing.T) {
+	env, err := Load("testdata/depots.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Depots) != 2 {
+		t.Fatalf("len(env.Depots) should be `2`, but `%v`", len(env.Depots))
+	}
+	if len(env.AllPos) != 28 {
+		t.Fatalf("len(env.AllPos) should be `28`, but `%v`", len(env.AllPos))
+	}
+	if !env.IsDepot(pos.New(6, 3)) {
+		t.Fatalf("(6, 3) should be a depot")
+	}
+	if env.IsDepot(pos.New(3, 3)) {
+		t.Fatalf("(3, 3) should not be a depot")
+	}
+	if !env.Accepts(pos.New(0, 3), 0) || !env.Accepts(pos.New(0, 3), 1) {
+		t.Fatalf("depot (0, 3) should accept every item type")
+	}
+	if env.Accepts(pos.New(6, 3), 0) || !env.Accepts(pos.New(6, 3), 1) {
+		t.Fatalf("depot (6, 3) should accept only item type `1`")
+	}
+	items := []item.Item{item.New(0, 0, 0, 0), item.New(1, 1, 0, 0), item.New(2, 1, 0, 0)}
+	if n := env.NumAccepted(pos.New(6, 3), items); n != 2 {
+		t.Fatalf("env.NumAccepted((6, 3)) should be `2`, but `%v`", n)
+	}
+	if env.MinDist[pos.New(6, 3)][pos.New(0, 3)] != 6 {
+		t.Fatalf("env.MinDist[(6, 3)][(0, 3)] should be `6`, but `%v`", env.MinDist[pos.New(6, 3)][pos.New(0, 3)])
+	}
+}
+
+func TestParseMapData(t *testing.T) {
+	mapData, err := loadMapData("testdata/typed_map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells, err := parseMapData(mapData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cells[0][0].Type != CellStart {
+		t.Fatalf("cells[0][0].Type should be `CellStart`, but `%v`", cells[0][0].Type)
+	}
+	if cells[0][6].Type != CellCharger {
+		t.Fatalf("cells[0][6].Type should be `CellCharger`, but `%v`", cells[0][6].Type)
+	}
+	if cells[1][2].Dir != action.UP || cells[1][4].Dir != action.DOWN {
+		t.Fatalf("cells[1][2].Dir and cells[1][4].Dir should be `UP` and `DOWN`, but `%v` and `%v`", cells[1][2].Dir, cells[1][4].Dir)
+	}
+	if cells[3][3].Weight != 2 {
+		t.Fatalf("cells[3][3].Weight should be `2`, but `%v`", cells[3][3].Weight)
+	}
+	if cells[4][3].Type != CellNoStop {
+		t.Fatalf("cells[4][3].Type should be `CellNoStop`, but `%v`", cells[4][3].Type)
+	}
+	if _, err := parseMapData([]string{"..?"}); err == nil {
+		t.Fatalf("parseMapData should fail on an unknown symbol")
+	}
+	if _, err := parseMapData([]string{"...", ".."}); err == nil {
+		t.Fatalf("parseMapData should fail on rows of different widths")
+	}
+}
+
+func TestLoadTypedMap(t *testing.T) {
+	env, err := Load("testdata/typed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Depots) != 2 {
+		t.Fatalf("len(env.Depots) should be `2`, but `%v`", len(env.Depots))
+	}
+	if !env.IsDepot(pos.New(0, 3)) || !env.IsDepot(pos.New(6, 6)) {
+		t.Fatalf("(0, 3) and (6, 6) should be depots")
+	}
+	if len(env.StartPos) != 2 {
+		t.Fatalf("len(env.StartPos) should be `2`, but `%v`", len(env.StartPos))
+	}
+	if len(env.ChargerPos) != 1 || env.ChargerPos[0] != pos.New(6, 0) {
+		t.Fatalf("env.ChargerPos should be `[(6, 0)]`, but `%v`", env.ChargerPos)
+	}
+	if len(env.AllPos) != 28 {
+		t.Fatalf("len(env.AllPos) should be `28`, but `%v`", len(env.AllPos))
+	}
+	if len(env.SpawnPos) != 25 {
+		t.Fatalf("len(env.SpawnPos) should be `25`, but `%v`", len(env.SpawnPos))
+	}
+	if env.CumWeights[len(env.CumWeights)-1] != 26 {
+		t.Fatalf("total spawn weight should be `26`, but `%v`", env.CumWeights[len(env.CumWeights)-1])
+	}
+	if env.MapData[0] != "...#..C" || env.MapData[1] != ".#^#v#." {
+		t.Fatalf("env.MapData should be normalized, but `%v`", env.MapData[:2])
+	}
+}
+
+func TestOneWay(t *testing.T) {
+	env, err := Load("testdata/typed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env.ValidMoves[pos.New(2, 1)]) != 1 {
+		t.Fatalf("len(env.ValidMoves[(2, 1)]) should be `1`, but `%v`", len(env.ValidMoves[pos.New(2, 1)]))
+	}
+	var (
+		from pos.Pos
+		to   pos.Pos
+	)
+	from = pos.New(2, 0)
+	to = pos.New(2, 3)
+	if env.MinDist[from][to] != 7 {
+		t.Fatalf("env.MinDist[(2, 0)][(2, 3)] should be `7`, but `%v`", env.MinDist[from][to])
+	}
+	if env.MinDist[to][from] != 3 {
+		t.Fatalf("env.MinDist[(2, 3)][(2, 0)] should be `3`, but `%v`", env.MinDist[to][from])
+	}
+}
+
+func TestLoadArrival(t *testing.T) {
+	env, err := Load("testdata/arrival.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Arrival.Model != ArrivalBatch {
+		t.Fatalf("env.Arrival.Model should be `BATCH`, but `%v`", env.Arrival.Model)
+	}
+	if env.Arrival.BatchMax != 2 {
+		t.Fatalf("env.Arrival.BatchMax should be `2`, but `%v`", env.Arrival.BatchMax)
+	}
+	if env.Arrival.CurvePeriod != 100 {
+		t.Fatalf("env.Arrival.CurvePeriod should be `100`, but `%v`", env.Arrival.CurvePeriod)
+	}
+	if env.CellAt(pos.New(6, 6)).Weight != 5 {
+		t.Fatalf("weight of (6, 6) should be `5`, but `%v`", env.CellAt(pos.New(6, 6)).Weight)
+	}
+	if env.CumWeights[len(env.CumWeights)-1] != 33 {
+		t.Fatalf("total spawn weight should be `33`, but `%v`", env.CumWeights[len(env.CumWeights)-1])
+	}
+	//デポ(0, 3)や壁(1, 1)の重みを上書きしてもアイテムは出現しない
+	for _, p := range []pos.Pos{pos.New(0, 3), pos.New(1, 1), pos.New(7, 0)} {
+		env.Arrival.HotSpots = []HotSpot{{Pos: p, Weight: 5}}
+		if err := setupArrival(env); err == nil {
+			t.Fatalf("hot spot on (%v, %v) should be rejected", p.X, p.Y)
+		}
+	}
+}
+
+func TestLoadTrace(t *testing.T) {
+	for _, path := range []string{"testdata/trace.csv", "testdata/trace.json"} {
+		trace, err := loadTrace(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trace) != 2 {
+			t.Fatalf("len(trace) should be `2`, but `%v` (%s)", len(trace), path)
+		}
+		if len(trace[2]) != 1 || trace[2][0] != pos.New(6, 0) {
+			t.Fatalf("trace[2] should be `[(6, 0)]`, but `%v` (%s)", trace[2], path)
+		}
+		if len(trace[5]) != 3 {
+			t.Fatalf("len(trace[5]) should be `3`, but `%v` (%s)", len(trace[5]), path)
+		}
+	}
+}
+
+func TestCheckTrace(t *testing.T) {
+	env, err := Load("testdata/typed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Trace = map[int][]pos.Pos{2: {pos.New(1, 0)}}
+	if err := checkTrace(env); err != nil {
+		t.Fatalf("trace item on a floor cell should be accepted, but `%v`", err)
+	}
+	//(3, 4)は止まれないマス, (6, 0)は充電ステーション
+	for _, p := range []pos.Pos{pos.New(3, 4), pos.New(6, 0), pos.New(0, 3), pos.New(3, 0)} {
+		env.Trace = map[int][]pos.Pos{2: {p}}
+		if err := checkTrace(env); err == nil {
+			t.Fatalf("trace item on (%v, %v) should be rejected", p.X, p.Y)
+		}
+	}
+}
+
+func TestLoadBattery(t *testing.T) {
+	env, err := Load("testdata/battery.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !env.UsesBattery() {
+		t.Fatal("env.UsesBattery() should be `true`")
+	}
+	if env.Battery.Reserve != 5 {
+		t.Fatalf("env.Battery.Reserve should be `5`, but `%v`", env.Battery.Reserve)
+	}
+	charger, d, reachable := env.NearestCharger(0, pos.New(0, 0), nil)
+	if !reachable || charger != pos.New(6, 0) || d != 12 {
+		t.Fatalf("nearest charger from (0, 0) should be `(6, 0)` at distance `12`, but `%v` at `%v`", charger, d)
+	}
+	if _, _, reachable := env.NearestCharger(0, pos.New(0, 0), map[pos.Pos]bool{pos.New(6, 0): true}); reachable {
+		t.Fatal("no charger should be available when the only charger is occupied")
+	}
+}
+
+func TestLoadNoise(t *testing.T) {
+	env, err := Load("testdata/noise.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !env.UsesNoise() || !env.UsesBreakdown() {
+		t.Fatal("env.UsesNoise() and env.UsesBreakdown() should be `true`")
+	}
+	if env.Noise.RepairMax != 3 {
+		t.Fatalf("env.Noise.RepairMax should be `3`, but `%v`", env.Noise.RepairMax)
+	}
+	env.Noise.SlipProb = 1.5
+	if err := setupNoise(env); err == nil {
+		t.Fatal("slip_prob 1.5 should be rejected")
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	env, err := Load("testdata/profiles.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Capacity(0) != 1 || env.Capacity(1) != 3 {
+		t.Fatalf("capacities should be `1, 3`, but `%v, %v`", env.Capacity(0), env.Capacity(1))
+	}
+	if !env.CanMove(0, 3) || env.CanMove(1, 3) || !env.CanMove(1, 4) {
+		t.Fatal("agent 1 should move only on even turns")
+	}
+	if !env.CanEnter(0, pos.New(6, 0)) || env.CanEnter(1, pos.New(6, 0)) {
+		t.Fatal("only agent 0 should enter the charger (6, 0)")
+	}
+	if moves := env.AgentMoves(1, pos.New(5, 0), 2); len(moves) != 1 || moves[0] != action.LEFT {
+		t.Fatalf("moves of agent 1 at (5, 0) should be `[LEFT]`, but `%v`", moves)
+	}
+	if _, reachable := env.Dist(1, pos.New(0, 0), pos.New(6, 0)); reachable {
+		t.Fatal("agent 1 should not reach the charger (6, 0)")
+	}
+	if d, _ := env.Dist(0, pos.New(0, 0), pos.New(6, 0)); d != 12 {
+		t.Fatalf("distance of agent 0 from (0, 0) to (6, 0) should be `12`, but `%v`", d)
+	}
+	if _, _, reachable := env.NearestCharger(1, pos.New(0, 0), nil); reachable {
+		t.Fatal("agent 1 should have no charger to head to")
+	}
+	if _, _, reachable := env.NearestCharger(0, pos.New(0, 0), nil); !reachable {
+		t.Fatal("agent 0 should head to the charger (6, 0)")
+	}
+	starts, ok := env.MatchStarts([]int{1, 0})
+	if !ok || starts[0] != env.StartPos[1] || starts[1] != env.StartPos[0] {
+		t.Fatalf("starts should follow the order `[%v %v]`, but `%v`", env.StartPos[1], env.StartPos[0], starts)
+	}
+	//エージェント1がSに進入できなければ初期位置を割り当てられない
+	env.AgentProfiles[1].allowed = map[int]bool{CellFloor: true}
+	if err := setupStarts(env); err == nil {
+		t.Fatal("start cells agent 1 can't enter should be rejected")
+	}
+	env.StartPos = nil
+	if err := setupStarts(env); err != nil {
+		t.Fatalf("agent 1 should start from a floor cell, but `%v`", err)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	env, err := Load("testdata/typed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !env.Visible(pos.New(0, 0), pos.New(6, 6)) {
+		t.Fatal("every cell should be visible without sensing radius")
+	}
+	env.Sensing = Sensing{Radius: 3, LineOfSight: true}
+	if !env.Visible(pos.New(0, 0), pos.New(2, 0)) {
+		t.Fatal("(2, 0) should be visible from (0, 0)")
+	}
+	if env.Visible(pos.New(0, 0), pos.New(0, 4)) {
+		t.Fatal("(0, 4) should be out of radius from (0, 0)")
+	}
+	if env.Visible(pos.New(2, 0), pos.New(4, 0)) {
+		t.Fatal("(4, 0) should be hidden by the wall (3, 0) from (2, 0)")
+	}
+	if err := setupSensing(env); err != nil {
+		t.Fatal(err)
+	}
+	env.Algorithms = []string{"GREEDY", "PP"}
+	if err := setupSensing(env); err == nil {
+		t.Fatal("sensing should be rejected with PP agents")
+	}
+}
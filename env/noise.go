@@ -0,0 +1,39 @@
+package env
+
+import "fmt"
+
+//Noise 行動の失敗とエージェントの故障の設定（全て0なら行動は必ず成功する）
+type Noise struct {
+	SlipProb       float64 `json:"slip_prob"`        //移動しようとしたときに進行方向と垂直な方向に滑る確率
+	PickupFailProb float64 `json:"pickup_fail_prob"` //アイテムを拾おうとして失敗する確率
+	BreakdownProb  float64 `json:"breakdown_prob"`   //各ターンにエージェントが故障する確率
+	RepairMin      int     `json:"repair_min"`       //故障してから動けるようになるまでのターン数の最小値（0なら1）
+	RepairMax      int     `json:"repair_max"`       //故障してから動けるようになるまでのターン数の最大値（RepairMin未満ならRepairMin）
+}
+
+//setupNoise 行動の失敗とエージェントの故障の設定を検証し, 省略された値を補う
+func setupNoise(env *Env) error {
+	noise := &env.Noise
+	for _, p := range []float64{noise.SlipProb, noise.PickupFailProb, noise.BreakdownProb} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("noise probability %v is out of [0, 1]", p)
+		}
+	}
+	if noise.RepairMin <= 0 {
+		noise.RepairMin = 1
+	}
+	if noise.RepairMax < noise.RepairMin {
+		noise.RepairMax = noise.RepairMin
+	}
+	return nil
+}
+
+//UsesNoise 行動の失敗とエージェントの故障のいずれかが有効かどうかを返す
+func (env *Env) UsesNoise() bool {
+	return env.Noise.SlipProb > 0 || env.Noise.PickupFailProb > 0 || env.Noise.BreakdownProb > 0
+}
+
+//UsesBreakdown エージェントの故障が有効かどうかを返す
+func (env *Env) UsesBreakdown() bool {
+	return env.Noise.BreakdownProb > 0
+}
@@ -0,0 +1,97 @@
+package env
+
+import "fmt"
+
+//setupPlanner 経路計画の設定を検証し, 省略された値を補う
+func setupPlanner(env *Env) error {
+	switch env.PathPlanner {
+	case "", "ONE_STEP", "WHCA":
+	default:
+		return fmt.Errorf("unknown path_planner `%s`", env.PathPlanner)
+	}
+	if env.WHCAWindow < 0 {
+		return fmt.Errorf("whca_window must not be negative")
+	}
+	if env.WHCAWindow == 0 {
+		env.WHCAWindow = 8
+	}
+	return nil
+}
+
+//UsesWHCA 衝突回避にWHCA*（予約表を使う協調A*）を使うかどうかを返す
+func (env *Env) UsesWHCA() bool {
+	return env.GreedyCA && env.PathPlanner == "WHCA"
+}
+
+//MAPFSolvers 全てのエージェントの経路をまとめて計画するアルゴリズム
+var MAPFSolvers = []string{"CBS", "ECBS", "PBS", "PP"}
+
+//MAPF CBS, ECBS, PBS, PPのエージェントが使う経路計画の設定
+type MAPF struct {
+	Weight   float64 `json:"ecbs_weight"` //ECBSで許す経路の長さの和の最適値に対する倍率（0なら1.5）
+	MaxNodes int     `json:"max_nodes"`   //CBS, ECBS, PBSで探索木の節点を展開する数の上限（0なら200）
+	Priority string  `json:"priority"`    //PPでの優先順位: DISTANCE（空の場合も. 目的地に近い順）, LOAD（持っているアイテムが多い順）, ROTATING（ターンごとに順番に入れ替える）
+
+	Horizon     int `json:"horizon"`      //ローリングホライズンで確定して実行する計画のステップ数（衝突もこのステップまでしか解消しない. 0なら経路の最後まで）
+	ReplanEvery int `json:"replan_every"` //ローリングホライズンで計画し直す間隔のターン数（0ならイベントが起きたときと確定した計画を実行し終えたときだけ）
+}
+
+//setupMAPF 経路計画の設定を検証し, 省略された値を補う
+func setupMAPF(env *Env) error {
+	used := 0
+	for _, solver := range MAPFSolvers {
+		if env.UsesAlgorithm(solver) {
+			used++
+		}
+	}
+	if used > 1 {
+		return fmt.Errorf("can't use more than one of %v together", MAPFSolvers)
+	}
+	if env.MAPF.Weight != 0 && env.MAPF.Weight < 1 {
+		return fmt.Errorf("ecbs_weight %v is less than 1", env.MAPF.Weight)
+	}
+	if env.MAPF.MaxNodes < 0 {
+		return fmt.Errorf("max_nodes must not be negative")
+	}
+	if env.MAPF.Horizon < 0 || env.MAPF.ReplanEvery < 0 {
+		return fmt.Errorf("horizon and replan_every must not be negative")
+	}
+	if env.MAPF.Horizon > 0 && env.MAPF.ReplanEvery > env.MAPF.Horizon {
+		return fmt.Errorf("replan_every %v is longer than horizon %v", env.MAPF.ReplanEvery, env.MAPF.Horizon)
+	}
+	switch env.MAPF.Priority {
+	case "", "DISTANCE", "LOAD", "ROTATING":
+	default:
+		return fmt.Errorf("unknown priority `%s`", env.MAPF.Priority)
+	}
+	if env.MAPF.Weight == 0 {
+		env.MAPF.Weight = 1.5
+	}
+	if env.MAPF.MaxNodes == 0 {
+		env.MAPF.MaxNodes = 200
+	}
+	return nil
+}
+
+//MAPFSolver 全てのエージェントの経路をまとめて計画するアルゴリズムの名前を返す（使うエージェントがいなければ空）
+func (env *Env) MAPFSolver() string {
+	for _, solver := range MAPFSolvers {
+		if env.UsesAlgorithm(solver) {
+			return solver
+		}
+	}
+	return ""
+}
+
+//UsesMAPF 全てのエージェントの経路をまとめて計画するエージェントがいるかどうかを返す
+func (env *Env) UsesMAPF() bool {
+	return env.MAPFSolver() != ""
+}
+
+//MAPFWeight 経路計画で許す経路の長さの和の最適値に対する倍率を返す（ECBS以外なら1）
+func (env *Env) MAPFWeight() float64 {
+	if env.UsesAlgorithm("ECBS") {
+		return env.MAPF.Weight
+	}
+	return 1
+}
@@ -0,0 +1,133 @@
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//cellTypeNames allowed_cellsで指定するマスの種類の名前
+var cellTypeNames = map[string]int{
+	"FLOOR":   CellFloor,
+	"DEPOT":   CellDepot,
+	"START":   CellStart,
+	"CHARGER": CellCharger,
+	"NO_STOP": CellNoStop,
+}
+
+//Profile エージェントの性能の設定（カートやフォークリフトなど）
+type Profile struct {
+	Name         string   `json:"name"`
+	Capacity     int      `json:"capacity"`      //同時に持てるアイテムの数（0ならMaxItems）
+	MoveEvery    int      `json:"move_every"`    //何ターンに1回移動できるか（0なら1で, 毎ターン移動できる）
+	AllowedCells []string `json:"allowed_cells"` //進入できるマスの種類（FLOOR, DEPOT, START, CHARGER, NO_STOP. 空なら全て）
+	allowed      map[int]bool
+}
+
+//setupProfiles エージェントの性能の設定を検証し, 進入できるマスが制限されたエージェントの行動と最短距離を求める
+func setupProfiles(env *Env) error {
+	if len(env.AgentProfiles) == 0 {
+		return nil
+	}
+	if len(env.AgentProfiles) != env.NumAgents {
+		return fmt.Errorf("%v agent profiles for %v agents", len(env.AgentProfiles), env.NumAgents)
+	}
+	env.AgentValidMoves = make([]map[pos.Pos][]int, env.NumAgents)
+	env.AgentMinDist = make([]map[pos.Pos]map[pos.Pos]int, env.NumAgents)
+	//同じマスの種類に制限されたエージェントは最短距離を共有する
+	cache := make(map[string]map[pos.Pos]map[pos.Pos]int)
+	for id := range env.AgentProfiles {
+		profile := &env.AgentProfiles[id]
+		if profile.Capacity < 0 || profile.MoveEvery < 0 {
+			return fmt.Errorf("agent %v: capacity and move_every must not be negative", id)
+		}
+		if profile.Capacity == 0 {
+			profile.Capacity = env.MaxItems
+		}
+		if profile.MoveEvery == 0 {
+			profile.MoveEvery = 1
+		}
+		if len(profile.AllowedCells) == 0 {
+			continue
+		}
+		profile.allowed = make(map[int]bool)
+		for _, name := range profile.AllowedCells {
+			cellType, exist := cellTypeNames[name]
+			if !exist {
+				return fmt.Errorf("agent %v: unknown cell type `%s`", id, name)
+			}
+			profile.allowed[cellType] = true
+		}
+		canEnter := func(p pos.Pos) bool {
+			return profile.allowed[env.CellAt(p).Type]
+		}
+		env.AgentValidMoves[id] = make(map[pos.Pos][]int)
+		for p, moves := range env.ValidMoves {
+			for _, move := range moves {
+				if canEnter(pos.NextPos(p, move, env.MapData)) {
+					env.AgentValidMoves[id][p] = append(env.AgentValidMoves[id][p], move)
+				}
+			}
+		}
+		names := append([]string{}, profile.AllowedCells...)
+		sort.Strings(names)
+		key := strings.Join(names, ",")
+		if _, exist := cache[key]; !exist {
+			cache[key] = make(map[pos.Pos]map[pos.Pos]int)
+			for p := range env.ValidMoves {
+				if canEnter(p) {
+					cache[key][p] = doBFSIn(env.MapData, p, canEnter)
+				}
+			}
+		}
+		env.AgentMinDist[id] = cache[key]
+	}
+	return nil
+}
+
+//Capacity あるエージェントが同時に持てるアイテムの数を返す
+func (env *Env) Capacity(id int) int {
+	if len(env.AgentProfiles) == 0 {
+		return env.MaxItems
+	}
+	return env.AgentProfiles[id].Capacity
+}
+
+//CanMove あるエージェントがあるターンに移動できるかどうかを返す
+func (env *Env) CanMove(id int, turn int) bool {
+	if len(env.AgentProfiles) == 0 {
+		return true
+	}
+	return turn%env.AgentProfiles[id].MoveEvery == 0
+}
+
+//CanEnter あるエージェントがある座標のマスに進入できるかどうかを返す
+func (env *Env) CanEnter(id int, p pos.Pos) bool {
+	if len(env.AgentProfiles) == 0 || env.AgentProfiles[id].allowed == nil {
+		return true
+	}
+	return env.AgentProfiles[id].allowed[env.CellAt(p).Type]
+}
+
+//AgentMoves あるエージェントがあるターンにある座標で選択できる移動のリストを返す
+func (env *Env) AgentMoves(id int, p pos.Pos, turn int) []int {
+	if !env.CanMove(id, turn) {
+		return []int{}
+	}
+	if env.AgentValidMoves == nil || env.AgentValidMoves[id] == nil {
+		return env.ValidMoves[p]
+	}
+	return env.AgentValidMoves[id][p]
+}
+
+//Dist あるエージェントにとっての2点間の最短距離を返す（たどり着けなければfalse）
+func (env *Env) Dist(id int, from pos.Pos, to pos.Pos) (int, bool) {
+	if env.AgentMinDist == nil || env.AgentMinDist[id] == nil {
+		d, reachable := env.MinDist[from][to]
+		return d, reachable
+	}
+	d, reachable := env.AgentMinDist[id][from][to]
+	return d, reachable
+}
@@ -0,0 +1,50 @@
+package env
+
+import (
+	"fmt"
+	"net/url"
+)
+
+//Remote 外部のコントローラの設定（REMOTEのエージェントが使う. commandかurlのどちらか一方を指定する）
+type Remote struct {
+	Command   []string `json:"command"`    //標準入出力で1行ずつJSONをやり取りするサブプロセスのコマンド（環境設定ファイルのディレクトリで実行する）
+	URL       string   `json:"url"`        //JSONをPOSTするlocalhostのHTTPのエンドポイント
+	TimeoutMS int      `json:"timeout_ms"` //行動を待つ時間（ミリ秒. 0なら1000）
+	Fallback  string   `json:"fallback"`   //時間内に有効な行動が返ってこなかったときの行動の決め方: GREEDY（空の場合も）, STAY
+	Dir       string   `json:"-"`          //コマンドを実行するディレクトリ
+}
+
+//setupRemote 外部のコントローラの設定を検証し, 省略された値を補う
+func setupRemote(env *Env, dir string) error {
+	remote := &env.Remote
+	remote.Dir = dir
+	if !env.UsesAlgorithm("REMOTE") {
+		return nil
+	}
+	if (len(remote.Command) == 0) == (remote.URL == "") {
+		return fmt.Errorf("exactly one of command and url is required for REMOTE agents")
+	}
+	if remote.URL != "" {
+		u, err := url.Parse(remote.URL)
+		if err != nil {
+			return fmt.Errorf("can't parse url `%s` (%s)", remote.URL, err)
+		}
+		switch u.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+		default:
+			return fmt.Errorf("url `%s` is not on localhost", remote.URL)
+		}
+	}
+	if remote.TimeoutMS < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	if remote.TimeoutMS == 0 {
+		remote.TimeoutMS = 1000
+	}
+	switch remote.Fallback {
+	case "", "GREEDY", "STAY":
+	default:
+		return fmt.Errorf("unknown fallback `%s`", remote.Fallback)
+	}
+	return nil
+}
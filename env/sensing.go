@@ -0,0 +1,74 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//Sensing エージェントの観測の設定（Radiusが0ならマップ全体を観測できる）
+type Sensing struct {
+	Radius      int  `json:"radius"`        //観測できるマンハッタン距離
+	LineOfSight bool `json:"line_of_sight"` //真なら壁に遮られたマスは観測できない
+	Memory      int  `json:"memory"`        //観測してから何ターン経つとアイテムの記憶を忘れるか（0なら忘れない）
+}
+
+//setupSensing 観測の設定を検証する
+func setupSensing(env *Env) error {
+	if env.Sensing.Radius < 0 || env.Sensing.Memory < 0 {
+		return fmt.Errorf("sensing radius and memory must not be negative")
+	}
+	//競売, 経路計画, 外部のコントローラは全てのエージェントをまとめて真の状態から行動を決めるので, 観測の制限とは組み合わせられない
+	if env.IsPartiallyObservable() {
+		for _, algo := range append([]string{"AUCTION", "REMOTE"}, MAPFSolvers...) {
+			if env.UsesAlgorithm(algo) {
+				return fmt.Errorf("sensing can't be used with %s agents", algo)
+			}
+		}
+	}
+	return nil
+}
+
+//IsPartiallyObservable 観測できる範囲が制限されているかどうかを返す
+func (env *Env) IsPartiallyObservable() bool {
+	return env.Sensing.Radius > 0
+}
+
+//Visible ある座標から別の座標のマスを観測できるかどうかを返す
+func (env *Env) Visible(from pos.Pos, to pos.Pos) bool {
+	if !env.IsPartiallyObservable() {
+		return true
+	}
+	if pos.Abs(from.X-to.X)+pos.Abs(from.Y-to.Y) > env.Sensing.Radius {
+		return false
+	}
+	if !env.Sensing.LineOfSight {
+		return true
+	}
+	//ブレゼンハムのアルゴリズムで2点を結ぶ線分上のマスをたどり, 途中に壁があれば観測できない
+	dx, dy := pos.Abs(to.X-from.X), -pos.Abs(to.Y-from.Y)
+	sx, sy := 1, 1
+	if from.X > to.X {
+		sx = -1
+	}
+	if from.Y > to.Y {
+		sy = -1
+	}
+	e := dx + dy
+	x, y := from.X, from.Y
+	for x != to.X || y != to.Y {
+		if (x != from.X || y != from.Y) && env.Cells[y][x].Type == CellWall {
+			return false
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y += sy
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//setupStarts 全てのエージェントに進入できる初期位置を選べることを検証する
+func setupStarts(env *Env) error {
+	if len(env.StartPos) > 0 {
+		order := make([]int, len(env.StartPos))
+		for k := range order {
+			order[k] = k
+		}
+		if _, ok := env.MatchStarts(order); !ok {
+			return fmt.Errorf("start cells can't be assigned to agents that can enter them")
+		}
+		return nil
+	}
+	//マップデータにSがなければ, 停止禁止でなく進入できるマスから選ぶ
+	for id := 0; id < env.NumAgents; id++ {
+		found := false
+		for _, p := range env.AllPos {
+			if !env.IsNoStop(p) && env.CanEnter(id, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("agent %v has no cell to start from", id)
+		}
+	}
+	return nil
+}
+
+//MatchStarts StartPosの添字を試す順に並べたものを受け取り, 各エージェントに進入できる初期位置を重複しないように割り当てる（割り当てられなければfalse）
+//（各エージェントは空いている候補のうち順番が早いものを選び, 空いていなければ先に選んだエージェントに別の候補へ移ってもらう）
+func (env *Env) MatchStarts(order []int) ([]pos.Pos, bool) {
+	owner := make(map[int]int) //StartPosの添字からそこを割り当てたエージェントへのマップ
+	var assign func(id int, visited map[int]bool) bool
+	assign = func(id int, visited map[int]bool) bool {
+		for _, k := range order {
+			if _, taken := owner[k]; !taken && !visited[k] && env.CanEnter(id, env.StartPos[k]) {
+				visited[k] = true
+				owner[k] = id
+				return true
+			}
+		}
+		for _, k := range order {
+			if visited[k] || !env.CanEnter(id, env.StartPos[k]) {
+				continue
+			}
+			visited[k] = true
+			if assign(owner[k], visited) {
+				owner[k] = id
+				return true
+			}
+		}
+		return false
+	}
+	for id := 0; id < env.NumAgents; id++ {
+		if !assign(id, make(map[int]bool)) {
+			return nil, false
+		}
+	}
+	starts := make([]pos.Pos, env.NumAgents)
+	for k, id := range owner {
+		starts[id] = env.StartPos[k]
+	}
+	return starts, true
+}
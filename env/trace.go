@@ -0,0 +1,111 @@
+package env
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//TraceEntry 注文の履歴の1行（あるターンにある座標にcount個のアイテムが出現する）
+type TraceEntry struct {
+	Turn  int `json:"turn"`
+	X     int `json:"x"`
+	Y     int `json:"y"`
+	Count int `json:"count"`
+}
+
+//loadTrace 注文の履歴をCSVファイル（turn,x,y,count）またはJSONファイル（TraceEntryの配列）から読み込み,
+//ターンからそのターンにアイテムが出現する座標のスライスへのマップを返す
+func loadTrace(path string) (map[int][]pos.Pos, error) {
+	var entries []TraceEntry
+	var err error
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		entries, err = loadTraceJSON(path)
+	} else {
+		entries, err = loadTraceCSV(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	trace := make(map[int][]pos.Pos)
+	for _, e := range entries {
+		for i := 0; i < e.Count; i++ {
+			trace[e.Turn] = append(trace[e.Turn], pos.New(e.X, e.Y))
+		}
+	}
+	return trace, nil
+}
+
+//checkTrace 注文の履歴のアイテムが全て出現できるマスにあるか確かめる
+//（止まれないマスや充電ステーションに出現したアイテムはエージェントが拾えない）
+func checkTrace(env *Env) error {
+	for turn, ps := range env.Trace {
+		for _, p := range ps {
+			if 0 > p.X || p.X >= env.MapDataW || 0 > p.Y || p.Y >= env.MapDataH || env.Cells[p.Y][p.X].Type == CellWall || env.IsDepot(p) {
+				return fmt.Errorf("trace item at turn %v (%v, %v) is not on a floor cell", turn, p.X, p.Y)
+			}
+			if env.IsNoStop(p) {
+				return fmt.Errorf("can't place trace item at turn %v on the no-stop cell (%v, %v)", turn, p.X, p.Y)
+			}
+			if env.IsCharger(p) {
+				return fmt.Errorf("can't place trace item at turn %v on the charger (%v, %v)", turn, p.X, p.Y)
+			}
+		}
+	}
+	return nil
+}
+
+//loadTraceJSON 注文の履歴をJSONファイルから読み込む
+func loadTraceJSON(path string) ([]TraceEntry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read `%s` (%s)", path, err)
+	}
+	var entries []TraceEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("can't decode `%s` (%s)", path, err)
+	}
+	return entries, nil
+}
+
+//loadTraceCSV 注文の履歴をCSVファイルから読み込む（1行目が数値でなければヘッダとして読み飛ばす）
+func loadTraceCSV(path string) ([]TraceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open `%s` (%s)", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 4
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("can't read `%s` (%s)", path, err)
+	}
+	entries := []TraceEntry{}
+	for i, record := range records {
+		values := make([]int, len(record))
+		for j, field := range record {
+			values[j], err = strconv.Atoi(field)
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("can't parse line %v of `%s` (%s)", i+1, path, err)
+		}
+		entries = append(entries, TraceEntry{Turn: values[0], X: values[1], Y: values[2], Count: values[3]})
+	}
+	return entries, nil
+}
@@ -1,3 +1,3 @@
 module github.com/Div9851/warehouse-sim
 
-go 1.14
+go 1.16
@@ -0,0 +1,157 @@
+package greedy
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/comm"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//pathLen メッセージで知らせる経路の長さ
+const pathLen = 5
+
+//GreedyComm 受け取ったメッセージの予約を使って, あるエージェントの行動を貪欲法で決定する
+//（Greedyのように全員の行動をまとめて決めるのではなく, 自分の行動と他のエージェントに送るメッセージを返す）
+func GreedyComm(id int, state *state.State, env *env.Env, rnd *rand.Rand, check bool, inbox []comm.Message) (int, comm.Message) {
+	now := state.AgentPos[id]
+	msg := comm.Message{From: id, SentTurn: state.Turn}
+	//故障中のエージェントはその場にとどまる
+	if state.AgentRepair != nil && state.AgentRepair[id] > 0 {
+		msg.Path = []pos.Pos{now}
+		return action.STAY, msg
+	}
+	reserved := make(map[pos.Pos]int)
+	blocked := make(map[pos.Pos]bool)
+	//他のエージェントが次のターンにいる予定の座標
+	planned := make(map[int]pos.Pos)
+	for _, m := range inbox {
+		//自分より目的地に近い（同じなら番号が小さい）エージェントの予約だけを尊重する
+		if m.HasClaim && !env.IsDepot(m.Claim) && hasPriority(m.From, id, m.Claim, state, env) {
+			reserved[m.Claim]++
+		}
+		//番号が小さいエージェントの移動の予定だけを避ける（互いに避け合って動けなくなるのを防ぐ）
+		if p, ok := m.NextPos(state.AgentPos[m.From]); ok && m.From < id {
+			planned[m.From] = p
+			blocked[p] = true
+		}
+	}
+	//すでにブロックされている, またはすれ違うような動き方でないか
+	canMoveTo := func(nxt pos.Pos) bool {
+		if !check {
+			return true
+		}
+		if blocked[nxt] {
+			return false
+		}
+		for other, p := range planned {
+			if state.AgentPos[other] == nxt && p == now {
+				return false
+			}
+		}
+		return true
+	}
+	ts := candidates(id, state, env)
+	sort.Sort(sort.Reverse(ts))
+	act := -1
+	for _, t := range ts {
+		if t.Value == 0 {
+			break
+		}
+		//他のエージェントがアイテム数と同じ数だけ予約していたらダメ
+		if !t.Charge && !env.IsDepot(t.Pos) && reserved[t.Pos] >= len(state.PosItems[t.Pos]) {
+			continue
+		}
+		//目的地にいるなら
+		if now == t.Pos {
+			if t.Charge {
+				act = action.STAY
+			} else if env.IsDepot(t.Pos) {
+				act = action.CLEAR
+			} else {
+				act = action.PICKUP
+			}
+		} else {
+			dist, _ := env.Dist(id, now, t.Pos)
+			moves := []int{}
+			for _, move := range env.AgentMoves(id, now, state.Turn) {
+				nxt := pos.NextPos(now, move, env.MapData)
+				//目的地に近づくなら
+				if d, reachable := env.Dist(id, nxt, t.Pos); canMoveTo(nxt) && reachable && dist > d {
+					moves = append(moves, move)
+				}
+			}
+			//目的地に近づく動き方がなければスキップ
+			if len(moves) == 0 {
+				continue
+			}
+			act = moves[rnd.Intn(len(moves))]
+		}
+		msg.HasClaim = true
+		msg.Claim = t.Pos
+		break
+	}
+	if act == -1 {
+		validMoves := env.AgentMoves(id, now, state.Turn)
+		moves := []int{}
+		for _, move := range validMoves {
+			if canMoveTo(pos.NextPos(now, move, env.MapData)) {
+				moves = append(moves, move)
+			}
+		}
+		if len(validMoves) == 0 {
+			act = action.STAY
+		} else if len(moves) == 0 {
+			act = validMoves[rnd.Intn(len(validMoves))]
+		} else {
+			act = moves[rnd.Intn(len(moves))]
+		}
+	}
+	msg.Path = plannedPath(id, pos.NextPos(now, act, env.MapData), msg, env)
+	return act, msg
+}
+
+//hasPriority あるエージェントが別のエージェントより優先してある座標に向かうべきかどうかを返す
+func hasPriority(other int, id int, target pos.Pos, state *state.State, env *env.Env) bool {
+	d1, ok1 := env.Dist(other, state.AgentPos[other], target)
+	d2, ok2 := env.Dist(id, state.AgentPos[id], target)
+	if !ok1 || !ok2 {
+		return ok1
+	}
+	if d1 != d2 {
+		return d1 < d2
+	}
+	return other < id
+}
+
+//plannedPath 次のターンにいる座標から, 予約した目的地へ最短距離で向かう経路（長さはpathLen以下）を返す
+func plannedPath(id int, nxt pos.Pos, msg comm.Message, env *env.Env) []pos.Pos {
+	path := []pos.Pos{nxt}
+	if !msg.HasClaim {
+		return path
+	}
+	cur := nxt
+	for len(path) < pathLen && cur != msg.Claim {
+		dist, reachable := env.Dist(id, cur, msg.Claim)
+		if !reachable {
+			break
+		}
+		found := false
+		for _, move := range env.ValidMoves[cur] {
+			p := pos.NextPos(cur, move, env.MapData)
+			if d, ok := env.Dist(id, p, msg.Claim); ok && d < dist {
+				cur = p
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		path = append(path, cur)
+	}
+	return path
+}
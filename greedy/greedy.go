@@ -7,18 +7,65 @@ import (
 
 	"github.com/Div9851/warehouse-sim/action"
 	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
 	"github.com/Div9851/warehouse-sim/pos"
 	"github.com/Div9851/warehouse-sim/state"
 )
 
 //あるエージェントにとっての, ある点の価値を返す
+//（デポの場合はそのデポが受け付けるアイテムの数に比例するので, 最も近い受け付け可能なデポの価値が高くなる）
 func eval(id int, pos pos.Pos, state *state.State, env *env.Env) float64 {
-	d := 1 + float64(env.MinDist[state.AgentPos[id]][pos])
-	if pos == env.DepotPos {
-		return float64(state.AgentItems[id]) * env.Reward / d
+	dist, reachable := env.Dist(id, state.AgentPos[id], pos)
+	//一方通行のせいでたどり着けないなら価値はない
+	if !reachable {
+		return 0
 	}
-	m := math.Min(float64(state.PosItems[pos]), float64(env.MaxItems-state.AgentItems[id]))
-	return m * env.Reward / d
+	d := 1 + float64(dist)
+	arrival := state.Turn + dist
+	if env.IsDepot(pos) {
+		accepted := []item.Item{}
+		for _, it := range state.AgentItems[id] {
+			if env.Accepts(pos, it.Type) {
+				accepted = append(accepted, it)
+			}
+		}
+		return float64(len(accepted)) * env.Reward / d * urgency(accepted, arrival, false, env)
+	}
+	m := math.Min(float64(len(state.PosItems[pos])), float64(env.Capacity(id)-len(state.AgentItems[id])))
+	if m <= 0 {
+		return 0
+	}
+	//拾うことになるアイテム
+	items := state.PosItems[pos][:int(m)]
+	//たどり着く前に消滅してしまうなら価値はない
+	if env.ItemLifetime > 0 && arrival-items[0].SpawnTurn >= env.ItemLifetime {
+		return 0
+	}
+	return m * env.Reward / d * urgency(items, arrival, true, env)
+}
+
+//urgency アイテムのスライス, たどり着くターン, アイテムが床に置かれているかどうかを受け取り, 期限の近さに応じた価値の倍率を返す
+func urgency(items []item.Item, arrival int, onFloor bool, env *env.Env) float64 {
+	if env.UrgencyCoef == 0 {
+		return 1
+	}
+	slack := math.MaxInt32
+	for _, it := range items {
+		if it.Deadline > 0 && it.Deadline-arrival < slack {
+			slack = it.Deadline - arrival
+		}
+		//拾われないまま消滅する場合も期限とみなす
+		if onFloor && env.ItemLifetime > 0 && it.SpawnTurn+env.ItemLifetime-1-arrival < slack {
+			slack = it.SpawnTurn + env.ItemLifetime - 1 - arrival
+		}
+	}
+	if slack == math.MaxInt32 {
+		return 1
+	}
+	if slack < 0 {
+		slack = 0
+	}
+	return 1 + env.UrgencyCoef/float64(1+slack)
 }
 
 type tuple struct {
@@ -26,6 +73,7 @@ type tuple struct {
 	Pos       pos.Pos
 	Value     float64
 	RandomVal float64
+	Charge    bool //充電ステーションに向かう場合に真
 }
 
 type tuples []tuple
@@ -52,8 +100,66 @@ func makeTuple(id int, pos pos.Pos, value float64, randomVal float64) tuple {
 	return tuple{ID: id, Pos: pos, Value: value, RandomVal: randomVal}
 }
 
+//NeedCharge あるエージェントが充電ステーションに向かうべきかどうかと, 向かう充電ステーションの座標を返す
+func NeedCharge(id int, state *state.State, env *env.Env) (pos.Pos, bool) {
+	if state.AgentBattery == nil {
+		return pos.Pos{}, false
+	}
+	b := state.AgentBattery[id]
+	now := state.AgentPos[id]
+	//充電ステーションにいるなら満タンになるまで充電する
+	if env.IsCharger(now) {
+		return now, b < env.Battery.Capacity
+	}
+	//他のエージェントが使っている充電ステーションは避ける（全て使われているなら最も近いものに向かう）
+	occupied := make(map[pos.Pos]bool)
+	for other, p := range state.AgentPos {
+		if other != id {
+			occupied[p] = true
+		}
+	}
+	charger, d, reachable := env.NearestCharger(id, now, occupied)
+	if !reachable {
+		charger, d, reachable = env.NearestCharger(id, now, nil)
+	}
+	if !reachable {
+		return pos.Pos{}, false
+	}
+	return charger, b <= d*env.Battery.MoveCost+env.Battery.Reserve
+}
+
+//candidates あるエージェントの目的地の候補のタプルを返す
+func candidates(id int, state *state.State, env *env.Env) tuples {
+	//電池が少ないなら何よりも先に充電ステーションに向かう
+	if charger, ok := NeedCharge(id, state, env); ok {
+		t := makeTuple(id, charger, math.Inf(1), state.RandomValues[charger])
+		t.Charge = true
+		return tuples{t}
+	}
+	ts := make(tuples, 0, len(state.PosItems)+len(env.Depots))
+	for pos := range state.PosItems {
+		ts = append(ts, makeTuple(id, pos, eval(id, pos, state, env), state.RandomValues[pos]))
+	}
+	for _, depot := range env.Depots {
+		ts = append(ts, makeTuple(id, depot.Pos, eval(id, depot.Pos, state, env), state.RandomValues[depot.Pos]))
+	}
+	return ts
+}
+
 //Greedy 貪欲法で行動を決定する
 func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int, []float64) {
+	ts := make(tuples, 0)
+	for id := 0; id < env.NumAgents; id++ {
+		ts = append(ts, candidates(id, state, env)...)
+	}
+	return decide(ts, state, env, rnd, check)
+}
+
+//decide 目的地の候補のタプルを価値の高い順に見て, 各エージェントの行動を決定する
+func decide(ts tuples, state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int, []float64) {
+	if check && env.UsesWHCA() {
+		return decideWHCA(ts, state, env, rnd)
+	}
 	reserved := make(map[pos.Pos]int)
 	blocked := make(map[pos.Pos]bool)
 	agentID := make(map[pos.Pos]int)
@@ -61,13 +167,15 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 	actions := make([]int, env.NumAgents)
 	values := make([]float64, env.NumAgents)
 	dest := make([]pos.Pos, env.NumAgents)
-	ts := make(tuples, 0)
 	for id := 0; id < env.NumAgents; id++ {
 		agentID[state.AgentPos[id]] = id
-		for pos := range state.PosItems {
-			ts = append(ts, makeTuple(id, pos, eval(id, pos, state, env), state.RandomValues[pos]))
+		//故障中のエージェントはその場にとどまる
+		if state.AgentRepair != nil && state.AgentRepair[id] > 0 {
+			decided[id] = true
+			actions[id] = action.STAY
+			dest[id] = state.AgentPos[id]
+			blocked[state.AgentPos[id]] = true
 		}
-		ts = append(ts, makeTuple(id, env.DepotPos, eval(id, env.DepotPos, state, env), state.RandomValues[env.DepotPos]))
 	}
 	sort.Sort(sort.Reverse(ts))
 	for _, t := range ts {
@@ -79,13 +187,15 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 			continue
 		}
 		//すでにアイテム数と同じ数のエージェントが予約していたらダメ
-		if t.Pos != env.DepotPos && reserved[t.Pos] == state.PosItems[t.Pos] {
+		if !t.Charge && !env.IsDepot(t.Pos) && reserved[t.Pos] == len(state.PosItems[t.Pos]) {
 			continue
 		}
 		//目的地にいるなら
 		if state.AgentPos[t.ID] == t.Pos {
 			decided[t.ID] = true
-			if t.Pos == env.DepotPos {
+			if t.Charge {
+				actions[t.ID] = action.STAY
+			} else if env.IsDepot(t.Pos) {
 				actions[t.ID] = action.CLEAR
 			} else {
 				actions[t.ID] = action.PICKUP
@@ -95,8 +205,9 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 			reserved[t.Pos]++
 			continue
 		}
+		dist, _ := env.Dist(t.ID, state.AgentPos[t.ID], t.Pos)
 		moves := []int{}
-		validMoves := env.ValidMoves[state.AgentPos[t.ID]]
+		validMoves := env.AgentMoves(t.ID, state.AgentPos[t.ID], state.Turn)
 		for _, move := range validMoves {
 			nxt := pos.NextPos(state.AgentPos[t.ID], move, env.MapData)
 			if check {
@@ -111,7 +222,7 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 				}
 			}
 			//目的地に近づくなら
-			if env.MinDist[state.AgentPos[t.ID]][t.Pos] > env.MinDist[nxt][t.Pos] {
+			if d, reachable := env.Dist(t.ID, nxt, t.Pos); reachable && dist > d {
 				moves = append(moves, move)
 			}
 		}
@@ -121,7 +232,9 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 		}
 		decided[t.ID] = true
 		actions[t.ID] = moves[rnd.Intn(len(moves))]
-		values[t.ID] = t.Value
+		if !t.Charge {
+			values[t.ID] = t.Value
+		}
 		nxt := pos.NextPos(state.AgentPos[t.ID], actions[t.ID], env.MapData)
 		dest[t.ID] = nxt
 		blocked[nxt] = true
@@ -133,7 +246,7 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 			continue
 		}
 		moves := []int{}
-		validMoves := env.ValidMoves[state.AgentPos[id]]
+		validMoves := env.AgentMoves(id, state.AgentPos[id], state.Turn)
 		for _, move := range validMoves {
 			nxt := pos.NextPos(state.AgentPos[id], move, env.MapData)
 			if check {
@@ -150,7 +263,9 @@ func Greedy(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int
 			moves = append(moves, move)
 		}
 		decided[id] = true
-		if len(moves) == 0 {
+		if len(validMoves) == 0 {
+			actions[id] = action.STAY
+		} else if len(moves) == 0 {
 			actions[id] = validMoves[rnd.Intn(len(validMoves))]
 		} else {
 			actions[id] = moves[rnd.Intn(len(moves))]
@@ -0,0 +1,128 @@
+package greedy
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/assign"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//コスト行列で使う値（Hungarianは無限大を扱えないので十分大きな有限の値を使う）
+const (
+	idleCost       = 1e6 //何もしないコスト
+	infeasibleCost = 1e9 //割り当てられないコスト
+	//割り当てられた目的地のタプルの価値に加える値（割り当てられた目的地を通常の候補より先に見る）
+	assignedBonus = 1e9
+)
+
+//allocate 各エージェントへの目的地の割り当てをハンガリアン法で求め, 目的地を返す（割り当てがなければfalse）
+//（列は床のアイテム1つずつと, 各エージェント専用の列からなる. 専用の列はデポへの帰還または待機を表す）
+func allocate(state *state.State, env *env.Env) ([]pos.Pos, []bool) {
+	//マップのキーの順序に依存しないように座標の順に並べる
+	itemPos := make([]pos.Pos, 0, len(state.PosItems))
+	for p := range state.PosItems {
+		itemPos = append(itemPos, p)
+	}
+	sort.Slice(itemPos, func(i, j int) bool {
+		if itemPos[i].Y != itemPos[j].Y {
+			return itemPos[i].Y < itemPos[j].Y
+		}
+		return itemPos[i].X < itemPos[j].X
+	})
+	cols := []pos.Pos{}
+	for _, p := range itemPos {
+		for range state.PosItems[p] {
+			cols = append(cols, p)
+		}
+	}
+	numItems := len(cols)
+	n := env.NumAgents
+	cost := make([][]float64, n)
+	private := make([]pos.Pos, n)
+	for id := 0; id < n; id++ {
+		cost[id] = make([]float64, numItems+n)
+		for j := range cost[id] {
+			cost[id][j] = infeasibleCost
+		}
+		cost[id][numItems+id] = idleCost
+		if state.AgentRepair != nil && state.AgentRepair[id] > 0 {
+			continue
+		}
+		//充電ステーションに向かうエージェントにはアイテムを割り当てない
+		if _, ok := NeedCharge(id, state, env); ok {
+			continue
+		}
+		now := state.AgentPos[id]
+		for j, p := range cols {
+			//拾えない, たどり着けない, たどり着く前に消滅するアイテムは割り当てない
+			if eval(id, p, state, env) == 0 {
+				continue
+			}
+			d, _ := env.Dist(id, now, p)
+			cost[id][j] = float64(d)
+		}
+		//アイテムを持っているなら, 最も近い受け付け可能なデポに帰ることができる
+		best := -1
+		for _, depot := range env.Depots {
+			if eval(id, depot.Pos, state, env) == 0 {
+				continue
+			}
+			if d, _ := env.Dist(id, now, depot.Pos); best == -1 || d < best {
+				best = d
+				private[id] = depot.Pos
+			}
+		}
+		if best != -1 {
+			cost[id][numItems+id] = float64(best)
+		}
+	}
+	assignment := assign.Hungarian(cost)
+	targets := make([]pos.Pos, n)
+	ok := make([]bool, n)
+	for id, j := range assignment {
+		if j < 0 || cost[id][j] >= idleCost {
+			continue
+		}
+		if j < numItems {
+			targets[id] = cols[j]
+		} else {
+			targets[id] = private[id]
+		}
+		ok[id] = true
+	}
+	return targets, ok
+}
+
+//Hungarian ハンガリアン法で求めた目的地の割り当てに従って行動を決定する
+func Hungarian(state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int, []float64) {
+	targets, ok := allocate(state, env)
+	return Pursue(targets, ok, state, env, rnd, check)
+}
+
+//Goals ハンガリアン法で求めた割り当てに, 充電ステーションに向かうエージェントの目的地を加えて返す（目的地がなければfalse）
+func Goals(state *state.State, env *env.Env) ([]pos.Pos, []bool) {
+	targets, ok := allocate(state, env)
+	for id := range targets {
+		if charger, charge := NeedCharge(id, state, env); charge {
+			targets[id], ok[id] = charger, true
+		}
+	}
+	return targets, ok
+}
+
+//Pursue 各エージェントに割り当てられた目的地に向かう行動を決定する（okが偽のエージェントには割り当てがない）
+//（充電ステーションに向かうエージェントと, 割り当てられた目的地に近づけないエージェントは, Greedyと同じように他の候補に向かう）
+func Pursue(targets []pos.Pos, ok []bool, state *state.State, env *env.Env, rnd *rand.Rand, check bool) ([]int, []float64) {
+	ts := make(tuples, 0)
+	for id := 0; id < env.NumAgents; id++ {
+		if ok[id] {
+			d, _ := env.Dist(id, state.AgentPos[id], targets[id])
+			ts = append(ts, makeTuple(id, targets[id], assignedBonus+1/float64(1+d), state.RandomValues[targets[id]]))
+		}
+		ts = append(ts, candidates(id, state, env)...)
+	}
+	return decide(ts, state, env, rnd, check)
+}
@@ -0,0 +1,100 @@
+package greedy
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/mapf"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//decideWHCA decideと同じ順序で目的地を見るが, 移動はWHCA*で予約表を避けながら計画した経路の最初の1ステップにする
+//（行動が先に決まったエージェントほど優先度が高く, 後のエージェントはその経路を避ける）
+func decideWHCA(ts tuples, state *state.State, env *env.Env, rnd *rand.Rand) ([]int, []float64) {
+	table := mapf.NewTable()
+	window := env.WHCAWindow
+	reserved := make(map[pos.Pos]int)
+	decided := make([]bool, env.NumAgents)
+	actions := make([]int, env.NumAgents)
+	values := make([]float64, env.NumAgents)
+	for id := 0; id < env.NumAgents; id++ {
+		//故障中のエージェントはその場にとどまる
+		if state.AgentRepair != nil && state.AgentRepair[id] > 0 {
+			decided[id] = true
+			actions[id] = action.STAY
+			table.ReservePath(id, []pos.Pos{state.AgentPos[id]}, window)
+		}
+	}
+	sort.Sort(sort.Reverse(ts))
+	for _, t := range ts {
+		if t.Value == 0 {
+			break
+		}
+		//すでに行動が決まっているならスキップ
+		if decided[t.ID] {
+			continue
+		}
+		//すでにアイテム数と同じ数のエージェントが予約していたらダメ
+		if !t.Charge && !env.IsDepot(t.Pos) && reserved[t.Pos] == len(state.PosItems[t.Pos]) {
+			continue
+		}
+		now := state.AgentPos[t.ID]
+		//目的地にいるなら
+		if now == t.Pos {
+			decided[t.ID] = true
+			if t.Charge {
+				actions[t.ID] = action.STAY
+			} else if env.IsDepot(t.Pos) {
+				actions[t.ID] = action.CLEAR
+			} else {
+				actions[t.ID] = action.PICKUP
+			}
+			table.ReservePath(t.ID, []pos.Pos{now}, window)
+			reserved[t.Pos]++
+			continue
+		}
+		path, ok := mapf.PlanWindowed(t.ID, now, t.Pos, state.Turn, window, table, env)
+		if !ok {
+			continue
+		}
+		//計画した経路で目的地に近づけなければスキップ
+		dist, _ := env.Dist(t.ID, now, t.Pos)
+		if d, _ := env.Dist(t.ID, path[len(path)-1], t.Pos); d >= dist {
+			continue
+		}
+		decided[t.ID] = true
+		actions[t.ID] = mapf.FirstAction(path, env)
+		if !t.Charge {
+			values[t.ID] = t.Value
+		}
+		table.ReservePath(t.ID, path, window)
+		reserved[t.Pos]++
+	}
+	for id := 0; id < env.NumAgents; id++ {
+		//すでに行動が決まっているならスキップ
+		if decided[id] {
+			continue
+		}
+		//予約されていない行動（とどまる場合を含む）から選ぶ
+		now := state.AgentPos[id]
+		moves := []int{}
+		if !env.IsNoStop(now) && table.IsFree(id, now, now, 0) {
+			moves = append(moves, action.STAY)
+		}
+		for _, move := range env.AgentMoves(id, now, state.Turn) {
+			if table.IsFree(id, now, pos.NextPos(now, move, env.MapData), 0) {
+				moves = append(moves, move)
+			}
+		}
+		decided[id] = true
+		actions[id] = action.STAY
+		if len(moves) > 0 {
+			actions[id] = moves[rnd.Intn(len(moves))]
+		}
+		table.ReservePath(id, []pos.Pos{now, pos.NextPos(now, actions[id], env.MapData)}, window)
+	}
+	return actions, values
+}
@@ -0,0 +1,90 @@
+package gym
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+)
+
+func TestServer(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.LastTurn = 5
+	e.Algorithms = []string{"GREEDY", "GREEDY", "GREEDY"}
+	client, server := net.Pipe()
+	defer client.Close()
+	go NewServer(e).serveConn(server)
+	r := bufio.NewReader(client)
+	send := func(req string, res interface{}) {
+		if _, err := client.Write([]byte(req + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(line, res); err != nil {
+			t.Fatalf("can't decode `%s` (%s)", line, err)
+		}
+	}
+	var res Response
+	for want := 0; want < 2; want++ {
+		res = Response{}
+		send(`{"cmd":"reset","seed":1}`, &res)
+		if res.Error != "" || res.EnvID != want || res.Observation.Turn != 1 {
+			t.Fatalf("reset should create env %v at turn 1, but `%+v`", want, res)
+		}
+	}
+	//同じシード値なら同じ初期状態になる
+	var again Response
+	send(`{"cmd":"reset","env_id":0,"seed":1}`, &again)
+	if again.Observation.AgentPos[0] != res.Observation.AgentPos[0] {
+		t.Fatalf("same seed should give the same start, but `%v` and `%v`", again.Observation.AgentPos, res.Observation.AgentPos)
+	}
+	res = Response{}
+	send(`{"cmd":"step","env_id":0,"actions":[6,6,7]}`, &res)
+	if res.Error == "" {
+		t.Fatal("invalid action should be rejected")
+	}
+	res = Response{}
+	send(`{"cmd":"step","env_id":0,"actions":[6,6,6]}`, &res)
+	if res.Error != "" || res.Observation.Turn != 2 || len(res.Rewards) != 3 || res.Done {
+		t.Fatalf("step should advance env 0 to turn 2, but `%+v`", res)
+	}
+	for id, act := range res.Info.Executed {
+		if act != action.STAY || res.Observation.AgentPos[id] != again.Observation.AgentPos[id] {
+			t.Fatalf("agent %v should stay, but executed %v", id, act)
+		}
+	}
+	//配列で送った要求はまとめて処理される
+	for turn := 3; turn <= 5; turn++ {
+		var batch []Response
+		send(`[{"cmd":"step","env_id":0,"actions":[-1,-1,-1]},{"cmd":"step","env_id":1,"actions":[-1,6,-1]}]`, &batch)
+		if len(batch) != 2 || batch[0].EnvID != 0 || batch[1].EnvID != 1 {
+			t.Fatalf("batch should return responses in order, but `%+v`", batch)
+		}
+		if batch[0].Observation.Turn != turn || batch[0].Done != (turn == 5) {
+			t.Fatalf("env 0 should be at turn %v, but `%+v`", turn, batch[0])
+		}
+		if turn == 5 && batch[0].Info.Result == nil {
+			t.Fatal("result should be returned when done")
+		}
+	}
+	res = Response{}
+	send(`{"cmd":"step","env_id":0,"actions":[6,6,6]}`, &res)
+	if res.Error == "" || !res.Done {
+		t.Fatalf("step after done should fail, but `%+v`", res)
+	}
+	res = Response{}
+	send(`{"cmd":"close","env_id":1}`, &res)
+	send(`{"cmd":"step","env_id":1,"actions":[6,6,6]}`, &res)
+	if res.Error == "" {
+		t.Fatal("closed env should be unknown")
+	}
+}
@@ -0,0 +1,67 @@
+package gym
+
+import (
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/sim"
+	"github.com/Div9851/warehouse-sim/wire"
+)
+
+//Request クライアントからの要求（1行に1つのJSON. 要求の配列を送ると並行して処理し, 応答の配列を返す）
+type Request struct {
+	Cmd     string `json:"cmd"`     //spec, reset, step, close
+	EnvID   *int   `json:"env_id"`  //対象の環境（resetで省略すれば新しい環境を作る）
+	Seed    int64  `json:"seed"`    //resetで使うシード値
+	Actions []int  `json:"actions"` //stepで各エージェントが行う行動（-1なら環境設定のアルゴリズムで決める）
+}
+
+//Response サーバーからの応答
+type Response struct {
+	EnvID       int               `json:"env_id"`
+	Observation *wire.Observation `json:"observation,omitempty"`
+	Rewards     []float64         `json:"rewards,omitempty"`
+	Done        bool              `json:"done"`
+	Info        *Info             `json:"info,omitempty"`
+	Spec        *Spec             `json:"spec,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+//Spec 環境の仕様
+type Spec struct {
+	NumAgents  int       `json:"num_agents"`
+	LastTurn   int       `json:"last_turn"`
+	MaxItems   int       `json:"max_items"`
+	MapData    []string  `json:"map_data"`
+	Depots     []pos.Pos `json:"depots"`
+	NumActions int       `json:"num_actions"`
+	Actions    []string  `json:"actions"` //行動の番号から名前への対応
+}
+
+//Info 1ステップの遷移の詳細
+type Info struct {
+	Executed     []int       `json:"executed"`         //実際に行われた行動（滑った場合などは選んだ行動と異なる）
+	Success      []bool      `json:"success"`          //各エージェントの行動が成功したかどうか
+	Appeared     []pos.Pos   `json:"appeared"`         //出現したアイテムの座標
+	TotalRewards []float64   `json:"total_rewards"`    //各エージェントが得た報酬の合計
+	Result       *sim.Result `json:"result,omitempty"` //終了したときのシミュレーションの結果
+}
+
+//actionNames 行動の番号から名前への対応
+var actionNames = []string{"UP", "DOWN", "LEFT", "RIGHT", "PICKUP", "CLEAR", "STAY"}
+
+//newSpec 環境設定から環境の仕様を返す
+func newSpec(env *env.Env) *Spec {
+	depots := make([]pos.Pos, len(env.Depots))
+	for i, depot := range env.Depots {
+		depots[i] = depot.Pos
+	}
+	return &Spec{
+		NumAgents:  env.NumAgents,
+		LastTurn:   env.LastTurn,
+		MaxItems:   env.MaxItems,
+		MapData:    env.MapData,
+		Depots:     depots,
+		NumActions: len(actionNames),
+		Actions:    actionNames,
+	}
+}
@@ -0,0 +1,211 @@
+package gym
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/sim"
+	"github.com/Div9851/warehouse-sim/wire"
+)
+
+//instance サーバーが持つ1つの環境
+type instance struct {
+	mu  sync.Mutex
+	sim *sim.Simulator
+}
+
+//Server 複数の環境をまとめて持ち, reset, stepの要求に応えるサーバー
+//（1つの接続で複数の環境を使うことも, 複数の接続から同時に使うこともできる）
+type Server struct {
+	env  *env.Env
+	mu   sync.Mutex
+	envs map[int]*instance
+	next int //次に作る環境の番号
+}
+
+//NewServer 環境設定を受け取り, まだ環境を持たないサーバーを返す
+func NewServer(env *env.Env) *Server {
+	return &Server{env: env, envs: make(map[int]*instance)}
+}
+
+//Serve 接続を受け付け, 接続ごとに要求を処理する（受け付けに失敗したらエラーを返す）
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+//serveConn 1つの接続から1行ずつ要求を読み, 応答を1行ずつ書き出す
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	w := bufio.NewWriter(conn)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ret interface{}
+		if line[0] == '[' {
+			var reqs []Request
+			if err := json.Unmarshal(line, &reqs); err != nil {
+				ret = Response{Error: fmt.Sprintf("can't decode request (%s)", err)}
+			} else {
+				ret = s.HandleBatch(reqs)
+			}
+		} else {
+			var req Request
+			if err := json.Unmarshal(line, &req); err != nil {
+				ret = Response{Error: fmt.Sprintf("can't decode request (%s)", err)}
+			} else {
+				ret = s.Handle(req)
+			}
+		}
+		if err := enc.Encode(ret); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+//HandleBatch 複数の要求を並行して処理し, 同じ順に応答を返す
+func (s *Server) HandleBatch(reqs []Request) []Response {
+	ret := make([]Response, len(reqs))
+	wg := &sync.WaitGroup{}
+	for i := range reqs {
+		wg.Add(1)
+		go func(idx int) {
+			ret[idx] = s.Handle(reqs[idx])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	return ret
+}
+
+//Handle 1つの要求を処理して応答を返す
+func (s *Server) Handle(req Request) Response {
+	switch req.Cmd {
+	case "spec":
+		return Response{Spec: newSpec(s.env)}
+	case "reset":
+		return s.reset(req)
+	case "step":
+		return s.step(req)
+	case "close":
+		return s.close(req)
+	default:
+		return Response{Error: fmt.Sprintf("unknown cmd `%s`", req.Cmd)}
+	}
+}
+
+//reset 環境をシード値から初期化し, 最初の観測を返す（env_idを省略すれば新しい環境を作る）
+func (s *Server) reset(req Request) Response {
+	s.mu.Lock()
+	id := s.next
+	if req.EnvID != nil {
+		id = *req.EnvID
+	}
+	inst, exist := s.envs[id]
+	if !exist {
+		if req.EnvID != nil {
+			s.mu.Unlock()
+			return Response{EnvID: id, Error: fmt.Sprintf("unknown env_id %v", id)}
+		}
+		inst = &instance{}
+		s.envs[id] = inst
+		s.next++
+	}
+	//作ったばかりの環境が初期化される前にstepされないように, 一覧を解放する前にロックする
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	s.mu.Unlock()
+	next, err := sim.New(s.env, req.Seed)
+	if err != nil {
+		return Response{EnvID: id, Error: err.Error()}
+	}
+	//前のシミュレータが起動した外部のコントローラを残さないように終了させる
+	if inst.sim != nil {
+		inst.sim.Close()
+	}
+	inst.sim = next
+	return Response{EnvID: id, Observation: wire.NewObservation(inst.sim.State, s.env), Done: inst.sim.State.Turn == s.env.LastTurn}
+}
+
+//step 環境を1ステップ進め, 観測, 報酬, 終了したかどうか, 遷移の詳細を返す
+func (s *Server) step(req Request) Response {
+	if req.EnvID == nil {
+		return Response{Error: "env_id is required"}
+	}
+	id := *req.EnvID
+	s.mu.Lock()
+	inst, exist := s.envs[id]
+	s.mu.Unlock()
+	if !exist {
+		return Response{EnvID: id, Error: fmt.Sprintf("unknown env_id %v", id)}
+	}
+	if len(req.Actions) != s.env.NumAgents {
+		return Response{EnvID: id, Error: fmt.Sprintf("%v actions for %v agents", len(req.Actions), s.env.NumAgents)}
+	}
+	for agent, act := range req.Actions {
+		if act < -1 || act >= action.NUM {
+			return Response{EnvID: id, Error: fmt.Sprintf("invalid action %v of agent %v", act, agent)}
+		}
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.sim == nil {
+		return Response{EnvID: id, Error: "env is not initialized (reset first)"}
+	}
+	if !inst.sim.Step(req.Actions) {
+		return Response{EnvID: id, Error: "episode is already done (reset first)", Done: true}
+	}
+	st := inst.sim.State
+	info := &Info{
+		Executed:     inst.sim.LastActions,
+		Success:      st.Success,
+		Appeared:     append([]pos.Pos{}, inst.sim.LastAppear...),
+		TotalRewards: append([]float64{}, inst.sim.TotalRewards...),
+	}
+	done := st.Turn == s.env.LastTurn
+	if done {
+		info.Result = inst.sim.GetResult()
+	}
+	return Response{EnvID: id, Observation: wire.NewObservation(inst.sim.State, s.env), Rewards: inst.sim.LastRewards, Done: done, Info: info}
+}
+
+//close 環境を一覧から取り除き, シミュレータを終了させる
+func (s *Server) close(req Request) Response {
+	if req.EnvID == nil {
+		return Response{Error: "env_id is required"}
+	}
+	id := *req.EnvID
+	s.mu.Lock()
+	inst, exist := s.envs[id]
+	delete(s.envs, id)
+	s.mu.Unlock()
+	if !exist {
+		return Response{EnvID: id, Error: fmt.Sprintf("unknown env_id %v", id)}
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.sim != nil {
+		inst.sim.Close()
+	}
+	return Response{EnvID: id, Done: true}
+}
@@ -0,0 +1,74 @@
+import json
+import random
+import socket
+import sys
+
+
+class Client:
+    """`cmd gym` で起動したサーバーに接続するクライアント"""
+
+    def __init__(self, addr="127.0.0.1:5555", network="tcp"):
+        if network == "unix":
+            self.sock = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+            self.sock.connect(addr)
+        else:
+            host, port = addr.rsplit(":", 1)
+            self.sock = socket.create_connection((host, int(port)))
+        self.file = self.sock.makefile("rw")
+
+    def request(self, req):
+        """要求（またはその配列）を送り, 応答を返す"""
+        self.file.write(json.dumps(req) + "\n")
+        self.file.flush()
+        ret = json.loads(self.file.readline())
+        for res in ret if isinstance(ret, list) else [ret]:
+            if "error" in res:
+                raise RuntimeError(res["error"])
+        return ret
+
+    def spec(self):
+        return self.request({"cmd": "spec"})["spec"]
+
+    def reset(self, seed, env_id=None):
+        """環境を初期化し, (env_id, observation) を返す"""
+        req = {"cmd": "reset", "seed": seed}
+        if env_id is not None:
+            req["env_id"] = env_id
+        res = self.request(req)
+        return res["env_id"], res["observation"]
+
+    def step(self, env_id, actions):
+        """1ステップ進め, (observation, rewards, done, info) を返す"""
+        res = self.request({"cmd": "step", "env_id": env_id, "actions": actions})
+        return res["observation"], res["rewards"], res["done"], res["info"]
+
+    def step_many(self, actions):
+        """env_idから行動へのdictを受け取り, 全ての環境を並行して1ステップ進める"""
+        reqs = [{"cmd": "step", "env_id": i, "actions": a} for i, a in actions.items()]
+        return {res["env_id"]: (res["observation"], res["rewards"], res["done"], res["info"]) for res in self.request(reqs)}
+
+    def close(self, env_id):
+        self.request({"cmd": "close", "env_id": env_id})
+
+
+def main():
+    # ランダムな方策で複数の環境を並行して動かす例
+    addr = sys.argv[1] if len(sys.argv) > 1 else "127.0.0.1:5555"
+    num_envs = int(sys.argv[2]) if len(sys.argv) > 2 else 4
+    client = Client(addr)
+    obs = dict(client.reset(seed) for seed in range(num_envs))
+    total = {i: 0.0 for i in obs}
+    done = False
+    while not done:
+        actions = {i: [random.choice(v) for v in o["valid_actions"]] for i, o in obs.items()}
+        results = client.step_many(actions)
+        for i, (o, rewards, done, info) in results.items():
+            obs[i] = o
+            total[i] += sum(rewards)
+    for i in obs:
+        print("env %d: total reward %.1f" % (i, total[i]))
+        client.close(i)
+
+
+if __name__ == "__main__":
+    main()
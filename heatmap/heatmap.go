@@ -0,0 +1,233 @@
+package heatmap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//Layers 出力するカウンタの名前
+var Layers = []string{"visits", "blocked", "spawns", "pickups"}
+
+//Heatmap マスごとのカウンタ（[y][x]の順に添字をとる）
+type Heatmap struct {
+	H       int     `json:"h"`
+	W       int     `json:"w"`
+	Runs    int     `json:"runs"`    //集計したシミュレーションの数
+	Visits  [][]int `json:"visits"`  //エージェントがそのマスにいたターン数
+	Blocked [][]int `json:"blocked"` //そのマスへの移動に失敗した回数
+	Spawns  [][]int `json:"spawns"`  //そのマスにアイテムが出現した回数
+	Pickups [][]int `json:"pickups"` //そのマスでアイテムが拾われた回数
+}
+
+//NewGrid 高さと幅を受け取り, 0で初期化されたグリッドを返す
+func NewGrid(H int, W int) [][]int {
+	grid := make([][]int, H)
+	for y := range grid {
+		grid[y] = make([]int, W)
+	}
+	return grid
+}
+
+//CloneGrid グリッドの複製を返す
+func CloneGrid(grid [][]int) [][]int {
+	ret := make([][]int, len(grid))
+	for y := range grid {
+		ret[y] = append([]int{}, grid[y]...)
+	}
+	return ret
+}
+
+//New 高さと幅を受け取り, 空のHeatmapを返す
+func New(H int, W int) *Heatmap {
+	return &Heatmap{
+		H:       H,
+		W:       W,
+		Visits:  NewGrid(H, W),
+		Blocked: NewGrid(H, W),
+		Spawns:  NewGrid(H, W),
+		Pickups: NewGrid(H, W),
+	}
+}
+
+//Clone 全てのカウンタを複製したHeatmapを返す
+func (hm *Heatmap) Clone() *Heatmap {
+	return &Heatmap{
+		H:       hm.H,
+		W:       hm.W,
+		Runs:    hm.Runs,
+		Visits:  CloneGrid(hm.Visits),
+		Blocked: CloneGrid(hm.Blocked),
+		Spawns:  CloneGrid(hm.Spawns),
+		Pickups: CloneGrid(hm.Pickups),
+	}
+}
+
+//Layer 名前を受け取り, そのカウンタのグリッドを返す
+func (hm *Heatmap) Layer(name string) ([][]int, error) {
+	switch name {
+	case "visits":
+		return hm.Visits, nil
+	case "blocked":
+		return hm.Blocked, nil
+	case "spawns":
+		return hm.Spawns, nil
+	case "pickups":
+		return hm.Pickups, nil
+	}
+	return nil, fmt.Errorf("unknown heatmap layer `%s`", name)
+}
+
+//Add 別のHeatmapのカウンタを足し合わせる
+func (hm *Heatmap) Add(other *Heatmap) error {
+	if hm.H != other.H || hm.W != other.W {
+		return fmt.Errorf("heatmap size mismatch (%vx%v and %vx%v)", hm.W, hm.H, other.W, other.H)
+	}
+	hm.Runs += other.Runs
+	for _, name := range Layers {
+		dst, _ := hm.Layer(name)
+		src, _ := other.Layer(name)
+		for y := range dst {
+			for x := range dst[y] {
+				dst[y][x] += src[y][x]
+			}
+		}
+	}
+	return nil
+}
+
+//WriteCSV あるカウンタのグリッドをCSVとして書き出す
+func (hm *Heatmap) WriteCSV(w io.Writer, name string) error {
+	grid, err := hm.Layer(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	for _, row := range grid {
+		record := make([]string, len(row))
+		for x, v := range row {
+			record[x] = strconv.Itoa(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+//wallColor 壁の色
+var wallColor = color.RGBA{R: 64, G: 64, B: 64, A: 255}
+
+//heatColor 0以上1以下の値を受け取り, 白→黄→赤のグラデーションの色を返す
+func heatColor(v float64) color.RGBA {
+	if v < 0.5 {
+		return color.RGBA{R: 255, G: 255, B: uint8(255 * (1 - 2*v)), A: 255}
+	}
+	return color.RGBA{R: 255, G: uint8(255 * (2 - 2*v)), B: 0, A: 255}
+}
+
+//cellColors マップデータとカウンタのグリッドを受け取り, 各マスの色を返す
+func cellColors(mapData []string, grid [][]int) [][]color.RGBA {
+	maxValue := 0
+	for _, row := range grid {
+		for _, v := range row {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+	colors := make([][]color.RGBA, len(grid))
+	for y, row := range grid {
+		colors[y] = make([]color.RGBA, len(row))
+		for x, v := range row {
+			if mapData[y][x] == '#' {
+				colors[y][x] = wallColor
+			} else if maxValue == 0 {
+				colors[y][x] = heatColor(0)
+			} else {
+				colors[y][x] = heatColor(float64(v) / float64(maxValue))
+			}
+		}
+	}
+	return colors
+}
+
+//WritePNG あるカウンタをマップデータに重ねたPNG画像を書き出す（1マスをscale×scaleピクセルで描く）
+func (hm *Heatmap) WritePNG(w io.Writer, mapData []string, name string, scale int) error {
+	grid, err := hm.Layer(name)
+	if err != nil {
+		return err
+	}
+	colors := cellColors(mapData, grid)
+	img := image.NewRGBA(image.Rect(0, 0, hm.W*scale, hm.H*scale))
+	for y := 0; y < hm.H*scale; y++ {
+		for x := 0; x < hm.W*scale; x++ {
+			//マスの境界線
+			if scale >= 4 && (x%scale == 0 || y%scale == 0) {
+				img.Set(x, y, color.RGBA{R: 192, G: 192, B: 192, A: 255})
+				continue
+			}
+			img.Set(x, y, colors[y/scale][x/scale])
+		}
+	}
+	return png.Encode(w, img)
+}
+
+//WriteSVG あるカウンタをマップデータに重ねたSVG画像を書き出す（各マスのtitleに値を入れる）
+func (hm *Heatmap) WriteSVG(w io.Writer, mapData []string, name string, scale int) error {
+	grid, err := hm.Layer(name)
+	if err != nil {
+		return err
+	}
+	colors := cellColors(mapData, grid)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\">\n", hm.W*scale, hm.H*scale)
+	for y, row := range grid {
+		for x, v := range row {
+			c := colors[y][x]
+			fmt.Fprintf(&b, "<rect x=\"%v\" y=\"%v\" width=\"%v\" height=\"%v\" fill=\"#%02x%02x%02x\" stroke=\"#c0c0c0\"><title>(%v, %v) %v</title></rect>\n",
+				x*scale, y*scale, scale, scale, c.R, c.G, c.B, x, y, v)
+		}
+	}
+	fmt.Fprintln(&b, "</svg>")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+//Export 全てのカウンタをCSV, PNG, SVGとしてディレクトリに書き出す
+func (hm *Heatmap) Export(dir string, mapData []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("can't create `%s` (%s)", dir, err)
+	}
+	for _, name := range Layers {
+		writers := []struct {
+			ext   string
+			write func(io.Writer) error
+		}{
+			{".csv", func(w io.Writer) error { return hm.WriteCSV(w, name) }},
+			{".png", func(w io.Writer) error { return hm.WritePNG(w, mapData, name, 16) }},
+			{".svg", func(w io.Writer) error { return hm.WriteSVG(w, mapData, name, 16) }},
+		}
+		for _, writer := range writers {
+			path := filepath.Join(dir, name+writer.ext)
+			f, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("can't create `%s` (%s)", path, err)
+			}
+			err = writer.write(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("can't write `%s` (%s)", path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package heatmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	a := New(2, 3)
+	b := New(2, 3)
+	a.Runs, b.Runs = 1, 1
+	a.Visits[1][2] = 3
+	b.Visits[1][2] = 4
+	b.Spawns[0][0] = 1
+	if err := a.Add(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Runs != 2 {
+		t.Fatalf("a.Runs should be `2`, but `%v`", a.Runs)
+	}
+	if a.Visits[1][2] != 7 {
+		t.Fatalf("a.Visits[1][2] should be `7`, but `%v`", a.Visits[1][2])
+	}
+	if a.Spawns[0][0] != 1 {
+		t.Fatalf("a.Spawns[0][0] should be `1`, but `%v`", a.Spawns[0][0])
+	}
+	if err := a.Add(New(3, 2)); err == nil {
+		t.Fatalf("Add should fail on heatmaps of different sizes")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	hm := New(2, 3)
+	hm.Pickups[0][1] = 2
+	hm.Pickups[1][2] = 5
+	var b strings.Builder
+	if err := hm.WriteCSV(&b, "pickups"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "0,2,0\n0,0,5\n"
+	if b.String() != expected {
+		t.Fatalf("csv should be `%v`, but `%v`", expected, b.String())
+	}
+	if err := hm.WriteCSV(&b, "unknown"); err == nil {
+		t.Fatalf("WriteCSV should fail on an unknown layer")
+	}
+}
@@ -0,0 +1,19 @@
+package item
+
+//Item アイテムを表す構造体
+type Item struct {
+	ID        int `json:"id"`         //出現した順に振られる番号
+	Type      int `json:"type"`       //アイテムの種類
+	SpawnTurn int `json:"spawn_turn"` //出現したターン
+	Deadline  int `json:"deadline"`   //このターンまでにデポに運ぶ必要がある（0なら期限なし）
+}
+
+//New 番号, アイテムの種類, 出現したターン, 期限を受け取りItemを返す
+func New(id int, itemType int, spawnTurn int, deadline int) Item {
+	return Item{ID: id, Type: itemType, SpawnTurn: spawnTurn, Deadline: deadline}
+}
+
+//IsLate あるターンに運ばれたときに期限を過ぎているかどうかを返す
+func (it Item) IsLate(turn int) bool {
+	return it.Deadline > 0 && turn > it.Deadline
+}
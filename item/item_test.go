@@ -0,0 +1,17 @@
+package item
+
+import "testing"
+
+func TestIsLate(t *testing.T) {
+	it := New(0, 0, 10, 40)
+	if it.IsLate(40) {
+		t.Fatalf("it.IsLate(40) should be false, but true")
+	}
+	if !it.IsLate(41) {
+		t.Fatalf("it.IsLate(41) should be true, but false")
+	}
+	it = New(1, 0, 10, 0)
+	if it.IsLate(1000) {
+		t.Fatalf("an item without deadline should never be late")
+	}
+}
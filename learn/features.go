@@ -0,0 +1,106 @@
+package learn
+
+import (
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Key 価値関数の表の状態の特徴
+type Key struct {
+	X          int `json:"x"`
+	Y          int `json:"y"`
+	Load       int `json:"load"`       //持っているアイテムの数
+	ItemDist   int `json:"item_dist"`  //最も近いアイテムまでの距離の区分（なければ-1）
+	ItemDir    int `json:"item_dir"`   //最も近いアイテムに近づく移動（なければSTAY）
+	DepotDist  int `json:"depot_dist"` //最も近い受け付け可能なデポまでの距離の区分（なければ-1）
+	DepotDir   int `json:"depot_dir"`  //最も近い受け付け可能なデポに近づく移動（なければSTAY）
+	Neighbours int `json:"neighbours"` //上下左右のマスに他のエージェントがいるかどうかのビットマスク
+}
+
+//Encode あるエージェントから見た状態の特徴を返す
+func Encode(id int, s *state.State, env *env.Env) Key {
+	now := s.AgentPos[id]
+	carried := s.AgentItems[id]
+	key := Key{X: now.X, Y: now.Y, Load: len(carried), ItemDist: -1, ItemDir: action.STAY, DepotDist: -1, DepotDir: action.STAY}
+	//マップのキーの順序に依存しないように, 距離が同じなら座標の順に選ぶ
+	if len(carried) < env.Capacity(id) {
+		var nearest pos.Pos
+		best := -1
+		for p := range s.PosItems {
+			if d, reachable := env.Dist(id, now, p); reachable && (best == -1 || d < best || (d == best && less(p, nearest))) {
+				nearest, best = p, d
+			}
+		}
+		if best != -1 {
+			key.ItemDist = bucket(best)
+			key.ItemDir = firstMove(id, now, nearest, s.Turn, env)
+		}
+	}
+	if len(carried) > 0 {
+		var nearest pos.Pos
+		best := -1
+		for _, depot := range env.Depots {
+			if env.NumAccepted(depot.Pos, carried) == 0 {
+				continue
+			}
+			if d, reachable := env.Dist(id, now, depot.Pos); reachable && (best == -1 || d < best) {
+				nearest, best = depot.Pos, d
+			}
+		}
+		if best != -1 {
+			key.DepotDist = bucket(best)
+			key.DepotDir = firstMove(id, now, nearest, s.Turn, env)
+		}
+	}
+	occupied := make(map[pos.Pos]bool)
+	for other, p := range s.AgentPos {
+		if other != id {
+			occupied[p] = true
+		}
+	}
+	for bit, move := range []int{action.UP, action.DOWN, action.LEFT, action.RIGHT} {
+		if nxt := pos.NextPos(now, move, env.MapData); nxt != now && occupied[nxt] {
+			key.Neighbours |= 1 << bit
+		}
+	}
+	return key
+}
+
+//bucket 距離を区分に分ける（近いほど細かく分ける）
+func bucket(d int) int {
+	switch {
+	case d <= 3:
+		return d
+	case d <= 7:
+		return 4
+	case d <= 15:
+		return 5
+	default:
+		return 6
+	}
+}
+
+//firstMove 最短経路に沿って目的地に近づく最初の移動を返す（目的地にいるならSTAY）
+func firstMove(id int, from pos.Pos, to pos.Pos, turn int, env *env.Env) int {
+	best, bestDist := action.STAY, -1
+	if d, _ := env.Dist(id, from, to); d == 0 {
+		return best
+	}
+	for _, move := range env.AgentMoves(id, from, turn) {
+		nxt := pos.NextPos(from, move, env.MapData)
+		if d, reachable := env.Dist(id, nxt, to); reachable && (bestDist == -1 || d < bestDist) {
+			best, bestDist = move, d
+		}
+	}
+	return best
+}
+
+//less 座標の順序（Y, Xの順に比べる）
+func less(a pos.Pos, b pos.Pos) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
@@ -0,0 +1,105 @@
+package learn
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+func newState(agentPos []pos.Pos, agentItems [][]item.Item, posItems map[pos.Pos][]item.Item) *state.State {
+	return state.New(1, agentItems, agentPos, posItems, map[pos.Pos]float64{}, make([]bool, len(agentPos)))
+}
+
+func TestEncode(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := item.Item{ID: 0}
+	s := newState([]pos.Pos{pos.New(3, 3), pos.New(4, 3), pos.New(2, 3)}, [][]item.Item{{}, {}, {it}}, map[pos.Pos][]item.Item{pos.New(6, 3): {it}})
+	want := Key{X: 3, Y: 3, ItemDist: 3, ItemDir: action.RIGHT, DepotDist: -1, DepotDir: action.STAY, Neighbours: 1<<3 | 1<<2}
+	if got := Encode(0, s, e); got != want {
+		t.Fatalf("agent 0 should be encoded as `%+v`, but `%+v`", want, got)
+	}
+	//アイテムを持てないエージェントはアイテムを探さず, デポに向かう
+	want = Key{X: 2, Y: 3, Load: 1, ItemDist: -1, ItemDir: action.STAY, DepotDist: 2, DepotDir: action.LEFT, Neighbours: 1 << 3}
+	if got := Encode(2, s, e); got != want {
+		t.Fatalf("agent 2 should be encoded as `%+v`, but `%+v`", want, got)
+	}
+}
+
+func TestValidActions(t *testing.T) {
+	e, err := env.Load("../env/testdata/depots.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//(6, 3)のデポは種類1のアイテムしか受け付けない
+	s := newState([]pos.Pos{pos.New(6, 3), pos.New(6, 3), pos.New(0, 0)}, [][]item.Item{{item.New(0, 0, 0, 0)}, {item.New(1, 1, 0, 0)}, {}}, map[pos.Pos][]item.Item{})
+	hasClear := func(acts []int) bool {
+		for _, act := range acts {
+			if act == action.CLEAR {
+				return true
+			}
+		}
+		return false
+	}
+	if hasClear(ValidActions(0, s, e)) {
+		t.Fatalf("agent 0 can't clear an item the depot rejects, but `%v`", ValidActions(0, s, e))
+	}
+	if !hasClear(ValidActions(1, s, e)) {
+		t.Fatalf("agent 1 should be able to clear, but `%v`", ValidActions(1, s, e))
+	}
+}
+
+func TestTrainAndLoad(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.LastTurn = 20
+	cfg := Config{Method: "SARSA", Alpha: 0.1, Gamma: 0.9, Epsilon: 0.5, EpsilonMin: 0.1, EpsilonDecay: 0.5}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLearner(e, cfg, rand.New(rand.NewSource(0)))
+	for i := 0; i < 3; i++ {
+		l.Episode(newState([]pos.Pos{pos.New(3, 0), pos.New(3, 3), pos.New(6, 6)}, make([][]item.Item, 3), map[pos.Pos][]item.Item{}))
+	}
+	if l.Policy.Episodes != 3 || l.Epsilon != 0.1 {
+		t.Fatalf("3 episodes with epsilon 0.1 expected, but %v episodes with epsilon %v", l.Policy.Episodes, l.Epsilon)
+	}
+	dir, err := ioutil.TempDir("", "learn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.json")
+	if err := l.Policy.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Method != "SARSA" || p.Episodes != 3 || len(p.Tables) != 3 {
+		t.Fatalf("loaded policy differs: method `%v`, %v episodes, %v tables", p.Method, p.Episodes, len(p.Tables))
+	}
+	for id, table := range l.Policy.Tables {
+		if len(table) == 0 || len(p.Tables[id]) != len(table) {
+			t.Fatalf("table of agent %v has %v states, but %v after loading", id, len(table), len(p.Tables[id]))
+		}
+		for key, q := range table {
+			if *p.Tables[id][key] != *q {
+				t.Fatalf("values of `%+v` differ after loading", key)
+			}
+		}
+	}
+}
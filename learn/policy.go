@@ -0,0 +1,175 @@
+package learn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Table あるエージェントの行動価値の表（状態の特徴から各行動の価値へのマップ）
+type Table map[Key]*[action.NUM]float64
+
+//entry ファイルに保存する表の1行
+type entry struct {
+	Key Key                 `json:"key"`
+	Q   [action.NUM]float64 `json:"q"`
+}
+
+//policyFile ファイルに保存する方策
+type policyFile struct {
+	Method   string    `json:"method"`
+	Episodes int       `json:"episodes"`
+	Tables   [][]entry `json:"tables"`
+}
+
+//Policy 学習した各エージェントの行動価値の表
+type Policy struct {
+	Method   string //Q, SARSA
+	Episodes int    //学習したエピソードの数
+	Tables   []Table
+}
+
+//NewPolicy エージェントの数だけ空の表を持つ方策を返す
+func NewPolicy(numAgents int, method string) *Policy {
+	tables := make([]Table, numAgents)
+	for i := range tables {
+		tables[i] = make(Table)
+	}
+	return &Policy{Method: method, Tables: tables}
+}
+
+//table あるエージェントの表を返す（学習したときよりエージェントが多ければ使い回す）
+func (p *Policy) table(id int) Table {
+	return p.Tables[id%len(p.Tables)]
+}
+
+//value ある状態の特徴と行動の価値を返す（表になければ0）
+func (p *Policy) value(id int, key Key, act int) float64 {
+	q, exist := p.table(id)[key]
+	if !exist {
+		return 0
+	}
+	return q[act]
+}
+
+//best 選択できる行動のうち価値が最大のものと, その価値を返す（同じ価値の行動が複数あればランダムに選ぶ）
+func (p *Policy) best(id int, key Key, valid []int, rnd *rand.Rand) (int, float64) {
+	ret, best, ties := action.STAY, 0.0, 0
+	for _, act := range valid {
+		v := p.value(id, key, act)
+		switch {
+		case ties == 0 || v > best:
+			ret, best, ties = act, v, 1
+		case v == best:
+			ties++
+			if rnd.Intn(ties) == 0 {
+				ret = act
+			}
+		}
+	}
+	return ret, best
+}
+
+//Act 学習した方策に従ってあるエージェントの行動を返す
+func (p *Policy) Act(id int, s *state.State, env *env.Env, rnd *rand.Rand) int {
+	act, _ := p.best(id, Encode(id, s, env), ValidActions(id, s, env), rnd)
+	return act
+}
+
+//ValidActions あるエージェントが選択できる行動を返す
+//（拾うのはアイテムがあって持てるとき, 置くのはデポが受け付けるアイテムを持っているときだけ）
+func ValidActions(id int, s *state.State, env *env.Env) []int {
+	now := s.AgentPos[id]
+	ret := append([]int{action.STAY}, env.AgentMoves(id, now, s.Turn)...)
+	if len(s.PosItems[now]) > 0 && len(s.AgentItems[id]) < env.Capacity(id) {
+		ret = append(ret, action.PICKUP)
+	}
+	if env.NumAccepted(now, s.AgentItems[id]) > 0 {
+		ret = append(ret, action.CLEAR)
+	}
+	return ret
+}
+
+//Save 方策をJSONファイルに書き出す
+func (p *Policy) Save(path string) error {
+	f := policyFile{Method: p.Method, Episodes: p.Episodes, Tables: make([][]entry, len(p.Tables))}
+	for i, table := range p.Tables {
+		f.Tables[i] = make([]entry, 0, len(table))
+		for key, q := range table {
+			f.Tables[i] = append(f.Tables[i], entry{Key: key, Q: *q})
+		}
+		//差分が読みやすいように座標の順に並べる
+		sortEntries(f.Tables[i])
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("can't encode policy (%s)", err)
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("can't write `%s` (%s)", path, err)
+	}
+	return nil
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]*Policy)
+)
+
+//Load 方策をJSONファイルから読み込む（同じファイルは一度だけ読み込み, 以降は同じ方策を返す）
+func Load(path string) (*Policy, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if p, exist := cache[path]; exist {
+		return p, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read `%s` (%s)", path, err)
+	}
+	var f policyFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("can't decode `%s` (%s)", path, err)
+	}
+	if len(f.Tables) == 0 {
+		return nil, fmt.Errorf("`%s` has no tables", path)
+	}
+	p := NewPolicy(len(f.Tables), f.Method)
+	p.Episodes = f.Episodes
+	for i, entries := range f.Tables {
+		for _, e := range entries {
+			q := e.Q
+			p.Tables[i][e.Key] = &q
+		}
+	}
+	cache[path] = p
+	return p, nil
+}
+
+//sortEntries 表の行を座標, 持っているアイテムの数, その他の特徴の順に並べる
+func sortEntries(entries []entry) {
+	keyLess := func(a Key, b Key) bool {
+		if pa, pb := (pos.Pos{X: a.X, Y: a.Y}), (pos.Pos{X: b.X, Y: b.Y}); pa != pb {
+			return less(pa, pb)
+		}
+		x := []int{a.Load, a.ItemDist, a.ItemDir, a.DepotDist, a.DepotDir, a.Neighbours}
+		y := []int{b.Load, b.ItemDist, b.ItemDir, b.DepotDist, b.DepotDir, b.Neighbours}
+		for k := range x {
+			if x[k] != y[k] {
+				return x[k] < y[k]
+			}
+		}
+		return false
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return keyLess(entries[i].Key, entries[j].Key)
+	})
+}
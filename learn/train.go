@@ -0,0 +1,118 @@
+package learn
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Config 学習の設定
+type Config struct {
+	Method       string  //Q（Q学習）, SARSA
+	Alpha        float64 //学習率
+	Gamma        float64 //割引率
+	Epsilon      float64 //ε-greedyでランダムに行動する確率の初期値
+	EpsilonMin   float64 //εの下限
+	EpsilonDecay float64 //1エピソードごとにεに掛ける値
+}
+
+//Validate 学習の設定を検証する
+func (cfg Config) Validate() error {
+	switch cfg.Method {
+	case "Q", "SARSA":
+	default:
+		return fmt.Errorf("unknown method `%s`", cfg.Method)
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha > 1 {
+		return fmt.Errorf("alpha %v is not in (0, 1]", cfg.Alpha)
+	}
+	if cfg.Gamma < 0 || cfg.Gamma > 1 {
+		return fmt.Errorf("gamma %v is not in [0, 1]", cfg.Gamma)
+	}
+	if cfg.Epsilon < 0 || cfg.Epsilon > 1 || cfg.EpsilonMin < 0 || cfg.EpsilonMin > cfg.Epsilon {
+		return fmt.Errorf("epsilon %v and epsilon_min %v are invalid", cfg.Epsilon, cfg.EpsilonMin)
+	}
+	if cfg.EpsilonDecay <= 0 || cfg.EpsilonDecay > 1 {
+		return fmt.Errorf("epsilon_decay %v is not in (0, 1]", cfg.EpsilonDecay)
+	}
+	return nil
+}
+
+//Learner 各エージェントの行動価値の表をstate.NextStateを環境として学習する
+type Learner struct {
+	env     *env.Env
+	cfg     Config
+	rnd     *rand.Rand
+	Policy  *Policy
+	Epsilon float64 //現在のε
+}
+
+//NewLearner 環境設定, 学習の設定, 乱数生成器を受け取り, 空の表から学習するLearnerを返す
+func NewLearner(env *env.Env, cfg Config, rnd *rand.Rand) *Learner {
+	return &Learner{env: env, cfg: cfg, rnd: rnd, Policy: NewPolicy(env.NumAgents, cfg.Method), Epsilon: cfg.Epsilon}
+}
+
+//choose ε-greedyで行動を選ぶ
+func (l *Learner) choose(id int, key Key, valid []int) int {
+	if l.rnd.Float64() < l.Epsilon {
+		return valid[l.rnd.Intn(len(valid))]
+	}
+	act, _ := l.Policy.best(id, key, valid, l.rnd)
+	return act
+}
+
+//update ある状態の特徴と行動の価値を目標値に近づける
+func (l *Learner) update(id int, key Key, act int, target float64) {
+	table := l.Policy.table(id)
+	q, exist := table[key]
+	if !exist {
+		q = new([action.NUM]float64)
+		table[key] = q
+	}
+	q[act] += l.cfg.Alpha * (target - q[act])
+}
+
+//Episode 初期状態から最後のターンまで1エピソード学習し, 全てのエージェントが得た報酬の合計を返す
+func (l *Learner) Episode(s *state.State) float64 {
+	n := l.env.NumAgents
+	keys := make([]Key, n)
+	acts := make([]int, n)
+	for id := 0; id < n; id++ {
+		keys[id] = Encode(id, s, l.env)
+		acts[id] = l.choose(id, keys[id], ValidActions(id, s, l.env))
+	}
+	total := 0.0
+	for s.Turn < l.env.LastTurn {
+		//エージェントが実際に行った行動ではなく, 選んだ行動の価値を更新する（滑った場合なども選んだ行動の結果とみなす）
+		nxt, _, _, rewards := state.NextState(s, acts, l.env, l.rnd)
+		done := nxt.Turn == l.env.LastTurn
+		for id := 0; id < n; id++ {
+			total += rewards[id]
+			key := Encode(id, nxt, l.env)
+			valid := ValidActions(id, nxt, l.env)
+			act := l.choose(id, key, valid)
+			target := rewards[id]
+			if !done {
+				if l.cfg.Method == "SARSA" {
+					target += l.cfg.Gamma * l.Policy.value(id, key, act)
+				} else {
+					_, best := l.Policy.best(id, key, valid, l.rnd)
+					target += l.cfg.Gamma * best
+				}
+			}
+			l.update(id, keys[id], acts[id], target)
+			keys[id], acts[id] = key, act
+		}
+		s = nxt
+	}
+	l.Policy.Episodes++
+	if l.Epsilon*l.cfg.EpsilonDecay > l.cfg.EpsilonMin {
+		l.Epsilon *= l.cfg.EpsilonDecay
+	} else {
+		l.Epsilon = l.cfg.EpsilonMin
+	}
+	return total
+}
@@ -0,0 +1,114 @@
+package mapf
+
+import (
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//ctNode CBSの制約木の節点
+type ctNode struct {
+	constraints []constraint
+	paths       [][]pos.Pos
+	lbs         []int //各エージェントの経路の長さの下限
+	cost        int   //経路の長さの和
+	lb          int   //経路の長さの和の下限
+	conflicts   int
+	first       conflict
+}
+
+//evaluate 経路から節点のコストと時刻windowまでの衝突を計算し, 衝突があるかどうかを返す
+func (n *ctNode) evaluate(window int) bool {
+	n.cost, n.lb = 0, 0
+	for a, path := range n.paths {
+		n.cost += len(path) - 1
+		n.lb += n.lbs[a]
+	}
+	c, count, found := findConflicts(n.paths, window)
+	n.first, n.conflicts = c, count
+	return found
+}
+
+//Solve 全てのエージェントの衝突しない経路を求める（経路は時刻0から順に並べた座標で, 経路が終わった後はゴールにとどまる）
+//（w=1ならCBSで経路の長さの和が最小の経路を, w>1ならECBSで最小の和のw倍以内の経路を求める.
+//windowが0でなければ時刻windowまでの衝突だけを解消する. 制約木の節点をmaxNodes個展開しても見つからなければ, それまでで衝突が最も少なかった経路とfalseを返す）
+func Solve(agents []Agent, turn int, w float64, maxNodes int, window int, env *env.Env) ([][]pos.Pos, bool) {
+	root := &ctNode{paths: make([][]pos.Pos, len(agents)), lbs: make([]int, len(agents))}
+	for a, agent := range agents {
+		path, lb, ok := lowLevel(agent, NewTable(), newOccupancy(a, root.paths), turn, w, env)
+		if !ok {
+			return nil, false
+		}
+		root.paths[a], root.lbs[a] = path, lb
+	}
+	if !root.evaluate(window) {
+		return root.paths, true
+	}
+	open := []*ctNode{root}
+	best := root
+	for expanded := 0; len(open) > 0 && expanded < maxNodes; expanded++ {
+		//下限のw倍以内のコストの節点のうち, 衝突が最も少ないものを選ぶ
+		lbMin := open[0].lb
+		for _, n := range open {
+			if n.lb < lbMin {
+				lbMin = n.lb
+			}
+		}
+		k := -1
+		for i, n := range open {
+			if float64(n.cost) > w*float64(lbMin) {
+				continue
+			}
+			if k == -1 || n.conflicts < open[k].conflicts || (n.conflicts == open[k].conflicts && n.cost < open[k].cost) {
+				k = i
+			}
+		}
+		//ECBSで下限が真の値より小さいときは, コストが最も小さい節点を選ぶ
+		if k == -1 {
+			for i, n := range open {
+				if k == -1 || n.cost < open[k].cost {
+					k = i
+				}
+			}
+		}
+		cur := open[k]
+		open[k] = open[len(open)-1]
+		open = open[:len(open)-1]
+		if cur.conflicts == 0 {
+			return cur.paths, true
+		}
+		for _, c := range []constraint{cur.first.A, cur.first.B} {
+			child := &ctNode{
+				constraints: append(append([]constraint{}, cur.constraints...), c),
+				paths:       append([][]pos.Pos{}, cur.paths...),
+				lbs:         append([]int{}, cur.lbs...),
+			}
+			path, lb, ok := lowLevel(agents[c.Agent], constraintTable(c.Agent, child.constraints), newOccupancy(c.Agent, child.paths), turn, w, env)
+			if !ok {
+				continue
+			}
+			child.paths[c.Agent], child.lbs[c.Agent] = path, lb
+			child.evaluate(window)
+			if child.conflicts < best.conflicts {
+				best = child
+			}
+			open = append(open, child)
+		}
+	}
+	return best.paths, false
+}
+
+//constraintTable あるエージェントに課された制約を予約表にして返す
+func constraintTable(agent int, constraints []constraint) *Table {
+	table := NewTable()
+	for _, c := range constraints {
+		if c.Agent != agent {
+			continue
+		}
+		if c.Edge {
+			table.forbidMove(c.Prev, c.Pos, c.T)
+		} else {
+			table.Reserve(-1, c.Pos, c.T)
+		}
+	}
+	return table
+}
@@ -0,0 +1,106 @@
+package mapf
+
+import "github.com/Div9851/warehouse-sim/pos"
+
+//constraint CBSの制約（Agent番目のエージェントは時刻Tに座標Posにいてはいけない. Edgeが真なら時刻T-1から時刻TにかけてPrevからPosに移動してはいけない）
+type constraint struct {
+	Agent int
+	Pos   pos.Pos
+	Prev  pos.Pos
+	T     int
+	Edge  bool
+}
+
+//conflict 2つのエージェントの衝突と, それを解消するためにそれぞれに課す制約
+type conflict struct {
+	A constraint
+	B constraint
+}
+
+//at 経路の時刻tの座標を返す（経路が終わった後はゴールにとどまる）
+func at(path []pos.Pos, t int) pos.Pos {
+	if t < len(path) {
+		return path[t]
+	}
+	return path[len(path)-1]
+}
+
+//findConflicts 全てのエージェントの経路を受け取り, 時刻windowまでで最も早い衝突と衝突の数を返す（衝突がなければfalse. windowが0なら経路の最後まで見る）
+//（state.nextPosOptと同じく, 同じマスへの移動, すれ違い, 3つ以上のエージェントの循環する移動を衝突とみなす.
+//移動するエージェントの後ろについていく移動は衝突ではない）
+func findConflicts(paths [][]pos.Pos, window int) (conflict, int, bool) {
+	maxLen := 0
+	for _, path := range paths {
+		if len(path) > maxLen {
+			maxLen = len(path)
+		}
+	}
+	if window > 0 && window+1 < maxLen {
+		maxLen = window + 1
+	}
+	var first conflict
+	found := false
+	count := 0
+	report := func(c conflict) {
+		if !found {
+			first = c
+			found = true
+		}
+		count++
+	}
+	for t := 1; t < maxLen; t++ {
+		prevID := make(map[pos.Pos]int)
+		nextID := make(map[pos.Pos]int)
+		for a, path := range paths {
+			prevID[at(path, t-1)] = a
+		}
+		for a, path := range paths {
+			p := at(path, t)
+			//同じマスへの移動
+			if b, exist := nextID[p]; exist {
+				report(conflict{A: constraint{Agent: b, Pos: p, T: t}, B: constraint{Agent: a, Pos: p, T: t}})
+				continue
+			}
+			nextID[p] = a
+		}
+		for a, path := range paths {
+			from, to := at(path, t-1), at(path, t)
+			if from == to {
+				continue
+			}
+			b, exist := prevID[to]
+			if !exist {
+				continue
+			}
+			//すれ違い（同じ組を2回数えないように番号の小さい方から見る）
+			if at(paths[b], t) == from {
+				if a < b {
+					report(conflict{
+						A: constraint{Agent: a, Pos: to, Prev: from, T: t, Edge: true},
+						B: constraint{Agent: b, Pos: from, Prev: to, T: t, Edge: true},
+					})
+				}
+				continue
+			}
+			//前にいるエージェントをたどって自分に戻ってくるなら循環している（循環の中で番号が最も小さいエージェントから見る）
+			cycle := true
+			for cur, steps := b, 0; cur != a; steps++ {
+				nxt := at(paths[cur], t)
+				if nxt == at(paths[cur], t-1) || cur < a || steps == len(paths) {
+					cycle = false
+					break
+				}
+				next, exist := prevID[nxt]
+				if !exist {
+					cycle = false
+					break
+				}
+				cur = next
+			}
+			if cycle {
+				report(conflict{A: constraint{Agent: a, Pos: to, T: t}, B: constraint{Agent: b, Pos: at(paths[b], t), T: t}})
+			}
+		}
+	}
+	return first, count, found
+}
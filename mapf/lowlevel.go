@@ -0,0 +1,178 @@
+package mapf
+
+import (
+	"container/heap"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//Agent CBSで経路を求めるエージェント
+type Agent struct {
+	ID    int //環境設定でのエージェントの番号（移動の制限に使う）
+	Start pos.Pos
+	Goal  pos.Pos
+	Delay int //この時刻までは移動できない（故障中など）
+}
+
+//focalNode 低レベル探索の節点（時刻がそのままコストになる）
+type focalNode struct {
+	Pos       pos.Pos
+	T         int
+	H         int
+	Conflicts int //ここまでの経路での他のエージェントの経路との衝突の数
+	Parent    int
+}
+
+//bucket Fが同じ節点の添字の優先度付きキュー（衝突が少なく, 同じならHが小さい順）
+type bucket struct {
+	nodes *[]focalNode
+	idx   []int
+}
+
+func (b bucket) Len() int {
+	return len(b.idx)
+}
+
+func (b bucket) Less(i, j int) bool {
+	return b.nodeLess(b.idx[i], b.idx[j])
+}
+
+func (b bucket) Swap(i, j int) {
+	b.idx[i], b.idx[j] = b.idx[j], b.idx[i]
+}
+
+func (b *bucket) Push(x interface{}) {
+	b.idx = append(b.idx, x.(int))
+}
+
+func (b *bucket) Pop() interface{} {
+	x := b.idx[len(b.idx)-1]
+	b.idx = b.idx[:len(b.idx)-1]
+	return x
+}
+
+//occupancy 他のエージェントの経路が各時刻にどこを使っているか
+type occupancy struct {
+	vertices map[vertex]int
+	edges    map[edge]int
+	holds    map[pos.Pos][]int //ゴールに着いた後にとどまり始める時刻
+}
+
+//newOccupancy あるエージェント以外の経路からoccupancyを作る
+func newOccupancy(self int, paths [][]pos.Pos) *occupancy {
+	occ := &occupancy{vertices: make(map[vertex]int), edges: make(map[edge]int), holds: make(map[pos.Pos][]int)}
+	for a, path := range paths {
+		if a == self || path == nil {
+			continue
+		}
+		for t := 0; t+1 < len(path); t++ {
+			occ.vertices[vertex{Pos: path[t], T: t}]++
+			occ.edges[edge{From: path[t], To: path[t+1], T: t + 1}]++
+		}
+		last := path[len(path)-1]
+		occ.holds[last] = append(occ.holds[last], len(path)-1)
+	}
+	return occ
+}
+
+//count 時刻t-1から時刻tにかけてfromからtoに移動するときの他のエージェントとの衝突の数を返す
+func (occ *occupancy) count(from pos.Pos, to pos.Pos, t int) int {
+	c := occ.vertices[vertex{Pos: to, T: t}]
+	for _, start := range occ.holds[to] {
+		if start <= t {
+			c++
+		}
+	}
+	if from != to {
+		c += occ.edges[edge{From: to, To: from, T: t}]
+	}
+	return c
+}
+
+//lowLevel 予約表を避けながらスタートからゴールに向かい, ゴールにとどまり続けられる経路を求める（経路がなければfalse）
+//（ゴールに着く時刻の下限のw倍以内の経路のうち, 他のエージェントの経路との衝突が少ないものを優先するフォーカル探索で, w=1なら最適な経路になる.
+//経路と, 探索を終えた時点での時刻の下限を返す）
+func lowLevel(agent Agent, table *Table, occ *occupancy, turn int, w float64, env *env.Env) ([]pos.Pos, int, bool) {
+	//ゴールにずっといる他のエージェントがいるならたどり着けない
+	if h, exist := table.holds[agent.Goal]; exist && h.ID != agent.ID {
+		return nil, 0, false
+	}
+	h0, reachable := env.Dist(agent.ID, agent.Start, agent.Goal)
+	if !reachable {
+		return nil, 0, false
+	}
+	//全てのマスを1回ずつ通れるだけの時間を探索の上限とする
+	horizon := table.maxT + agent.Delay + len(env.AllPos) + len(env.Depots)
+	nodes := []focalNode{{Pos: agent.Start, T: 0, H: h0, Parent: -1}}
+	buckets := map[int]*bucket{h0: {nodes: &nodes, idx: []int{0}}}
+	numOpen := 1
+	fmin := h0
+	closed := make(map[vertex]bool)
+	for numOpen > 0 {
+		for buckets[fmin] == nil || buckets[fmin].Len() == 0 {
+			fmin++
+		}
+		//下限のw倍以内の節点のうち, 衝突が最も少ないものを選ぶ
+		best := -1
+		for f := fmin; float64(f) <= w*float64(fmin); f++ {
+			b := buckets[f]
+			if b == nil || b.Len() == 0 {
+				continue
+			}
+			if best == -1 || b.nodeLess(b.idx[0], buckets[best].idx[0]) {
+				best = f
+			}
+		}
+		cur := heap.Pop(buckets[best]).(int)
+		numOpen--
+		n := nodes[cur]
+		if n.Pos == agent.Goal && table.canStayFrom(agent.ID, n.Pos, n.T) {
+			path := make([]pos.Pos, n.T+1)
+			for k := cur; k != -1; k = nodes[k].Parent {
+				path[nodes[k].T] = nodes[k].Pos
+			}
+			return path, fmin, true
+		}
+		if closed[vertex{Pos: n.Pos, T: n.T}] || n.T == horizon {
+			continue
+		}
+		closed[vertex{Pos: n.Pos, T: n.T}] = true
+		nexts := []pos.Pos{}
+		//故障中はとどまるしかなく, 停止禁止のマスではとどまれない
+		if n.T < agent.Delay || !env.IsNoStop(n.Pos) {
+			nexts = append(nexts, n.Pos)
+		}
+		if n.T >= agent.Delay {
+			for _, move := range env.AgentMoves(agent.ID, n.Pos, turn+n.T) {
+				nexts = append(nexts, pos.NextPos(n.Pos, move, env.MapData))
+			}
+		}
+		for _, nxt := range nexts {
+			t := n.T + 1
+			if closed[vertex{Pos: nxt, T: t}] || !table.IsFree(agent.ID, n.Pos, nxt, n.T) {
+				continue
+			}
+			h, reachable := env.Dist(agent.ID, nxt, agent.Goal)
+			if !reachable {
+				continue
+			}
+			nodes = append(nodes, focalNode{Pos: nxt, T: t, H: h, Conflicts: n.Conflicts + occ.count(n.Pos, nxt, t), Parent: cur})
+			if buckets[t+h] == nil {
+				buckets[t+h] = &bucket{nodes: &nodes}
+			}
+			heap.Push(buckets[t+h], len(nodes)-1)
+			numOpen++
+		}
+	}
+	return nil, 0, false
+}
+
+//nodeLess 添字がiの節点が添字がjの節点より優先されるかどうかを返す
+func (b bucket) nodeLess(i int, j int) bool {
+	x, y := (*b.nodes)[i], (*b.nodes)[j]
+	if x.Conflicts != y.Conflicts {
+		return x.Conflicts < y.Conflicts
+	}
+	return x.H < y.H
+}
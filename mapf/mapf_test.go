@@ -0,0 +1,124 @@
+package mapf
+
+import (
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+func TestPlanWindowed(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTable()
+	//エージェント0は通路を左から右へ進む
+	first, ok := PlanWindowed(0, pos.New(0, 3), pos.New(6, 3), 0, 8, table, e)
+	if !ok || len(first) != 7 {
+		t.Fatalf("path of agent 0 should have 7 cells, but `%v`", first)
+	}
+	table.ReservePath(0, first, 8)
+	//エージェント1は同じ通路を右から左へ進むので, 脇の通路に避けて待つ必要がある
+	second, ok := PlanWindowed(1, pos.New(6, 3), pos.New(0, 3), 0, 8, table, e)
+	if !ok {
+		t.Fatal("agent 1 should find a path")
+	}
+	for k := 0; k+1 < len(second); k++ {
+		if second[k+1] != second[k] && e.MinDist[second[k]][second[k+1]] != 1 {
+			t.Fatalf("agent 1 jumps from `%v` to `%v`", second[k], second[k+1])
+		}
+		if !table.IsFree(1, second[k], second[k+1], k) {
+			t.Fatalf("agent 1 collides with agent 0 at time %v (`%v`)", k+1, second)
+		}
+	}
+	if second[len(second)-1] == pos.New(6, 3) {
+		t.Fatalf("agent 1 should make progress within the window, but `%v`", second)
+	}
+	aside := false
+	for _, p := range second {
+		aside = aside || p.Y != 3
+	}
+	if !aside {
+		t.Fatalf("agent 1 should step aside from the aisle, but `%v`", second)
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	a, b, c := pos.New(0, 0), pos.New(1, 0), pos.New(1, 1)
+	//すれ違い
+	if _, count, found := findConflicts([][]pos.Pos{{a, b}, {b, a}}, 0); !found || count != 1 {
+		t.Fatalf("swap should be 1 conflict, but %v", count)
+	}
+	//時刻windowより後の衝突は見ない
+	if _, _, found := findConflicts([][]pos.Pos{{a, a, b}, {b, b, a}}, 1); found {
+		t.Fatal("conflict after the window should be ignored")
+	}
+	//後ろについていく移動は衝突ではない
+	if _, _, found := findConflicts([][]pos.Pos{{a, b}, {b, c}}, 0); found {
+		t.Fatal("following should not be a conflict")
+	}
+	//3つのエージェントの循環
+	d := pos.New(0, 1)
+	if _, count, found := findConflicts([][]pos.Pos{{a, b}, {b, c}, {c, d}, {d, a}}, 0); !found || count != 1 {
+		t.Fatalf("rotation should be 1 conflict, but %v", count)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//通路の両端のエージェントが入れ替わる
+	agents := []Agent{
+		{ID: 0, Start: pos.New(0, 3), Goal: pos.New(6, 3)},
+		{ID: 1, Start: pos.New(6, 3), Goal: pos.New(0, 3)},
+	}
+	for _, w := range []float64{1, 1.5} {
+		paths, ok := Solve(agents, 0, w, 100, 0, e)
+		if !ok {
+			t.Fatalf("w=%v: no solution", w)
+		}
+		if _, _, found := findConflicts(paths, 0); found {
+			t.Fatalf("w=%v: paths should not conflict, but `%v`", w, paths)
+		}
+		cost := len(paths[0]) + len(paths[1]) - 2
+		//片方が脇の通路に1マス避け, もう片方が通り過ぎるのを待って戻るのが最適
+		if (w == 1 && cost != 15) || float64(cost) > w*15 {
+			t.Fatalf("w=%v: sum of costs should be 15, but %v", w, cost)
+		}
+	}
+}
+
+func TestPrioritizedAndPBS(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agents := []Agent{
+		{ID: 0, Start: pos.New(0, 3), Goal: pos.New(6, 3)},
+		{ID: 1, Start: pos.New(6, 3), Goal: pos.New(0, 3)},
+		{ID: 2, Start: pos.New(2, 0), Goal: pos.New(2, 0)},
+	}
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}} {
+		paths, ok := Prioritized(agents, order, 0, e)
+		if !ok {
+			t.Fatalf("order %v: no solution", order)
+		}
+		if _, _, found := findConflicts(paths, 0); found {
+			t.Fatalf("order %v: paths should not conflict, but `%v`", order, paths)
+		}
+	}
+	paths, ok := PBS(agents, 0, 100, 0, e)
+	if !ok {
+		t.Fatal("PBS: no solution")
+	}
+	if _, _, found := findConflicts(paths, 0); found {
+		t.Fatalf("PBS: paths should not conflict, but `%v`", paths)
+	}
+	//動く必要のないエージェントはとどまる
+	if len(paths[2]) != 1 {
+		t.Fatalf("PBS: agent 2 should stay, but `%v`", paths[2])
+	}
+}
@@ -0,0 +1,265 @@
+package mapf
+
+import (
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Stats 経路計画の統計
+type Stats struct {
+	Replans       int `json:"replans"`        //計画した回数
+	Failures      int `json:"failures"`       //衝突しない経路が見つからなかった回数
+	Periodic      int `json:"periodic"`       //一定のターンが経った, または確定した計画を実行し終えたので計画し直した回数
+	OnItem        int `json:"on_item"`        //新しいアイテムが出現したので計画し直した回数
+	OnGoal        int `json:"on_goal"`        //目的地の割り当てが変わったので計画し直した回数
+	OnFailure     int `json:"on_failure"`     //計画通りに進めなかったエージェントがいるので計画し直した回数
+	Executed      int `json:"executed"`       //計画し直すまでに実行したステップ数の合計
+	ChangedAgents int `json:"changed_agents"` //計画し直したときに確定していた経路が変わったエージェントの数の合計
+	ChangedSteps  int `json:"changed_steps"`  //計画し直したときに確定していた経路のうち, 変わった（エージェント, ターン）の数の合計
+}
+
+//計画し直す理由
+const (
+	noReplan = iota
+	replanFirst
+	replanPeriodic
+	replanItem
+	replanGoal
+	replanFailure
+)
+
+//Planner CBS, ECBS, PBS, PPのいずれかで求めた経路に従って全てのエージェントを動かすプランナー
+//（新しいアイテムが出現したとき, 目的地の割り当てが変わったとき, 計画通りに進めなかったエージェントがいるときに計画し直す.
+//ローリングホライズンでは, 計画のうち最初のhorizonステップだけを確定して実行し, replan_everyターンごとにも計画し直す）
+type Planner struct {
+	env     *env.Env
+	Solver  string      //CBS, ECBS, PBS, PP
+	Weight  float64     //CBS, ECBSで許す経路の長さの和の最適値に対する倍率（1ならCBS, 1より大きければECBS）
+	Paths   [][]pos.Pos //計画した各エージェントの経路
+	Goals   []pos.Pos   //計画したときの各エージェントの目的地
+	HasGoal []bool      //計画したときに目的地があったかどうか
+	start   int         //経路を計画したターン
+	Stats   Stats
+}
+
+//NewPlanner 環境設定を受け取り, まだ何も計画していないPlannerを返す
+func NewPlanner(env *env.Env) *Planner {
+	return &Planner{env: env, Solver: env.MAPFSolver(), Weight: env.MAPFWeight()}
+}
+
+//Clone 複製したPlannerを返す（経路と目的地は計画し直すたびに作り直され書き換えられないので共有する）
+func (p *Planner) Clone() *Planner {
+	c := *p
+	return &c
+}
+
+//Update 現在の状態, 各エージェントの目的地（目的地がなければokが偽）, 直前に出現したアイテムの座標を受け取り, 必要なら計画し直す
+func (p *Planner) Update(s *state.State, goals []pos.Pos, ok []bool, appeared []pos.Pos) {
+	reason := p.needReplan(s, goals, ok, appeared)
+	if reason == noReplan {
+		return
+	}
+	env := p.env
+	agents := make([]Agent, env.NumAgents)
+	//同じ目的地に向かうエージェントがいるなら, 最も近いエージェント以外は目的地の近くの空いているマスで待つ（ゴールにとどまり続けられるのは1人だけなので）
+	claimed := make(map[pos.Pos]int)
+	for id := range agents {
+		if !ok[id] {
+			continue
+		}
+		d, _ := env.Dist(id, s.AgentPos[id], goals[id])
+		if other, exist := claimed[goals[id]]; exist {
+			if od, _ := env.Dist(other, s.AgentPos[other], goals[id]); od <= d {
+				continue
+			}
+		}
+		claimed[goals[id]] = id
+	}
+	for id := range agents {
+		agents[id] = Agent{ID: id, Start: s.AgentPos[id], Goal: s.AgentPos[id]}
+		if ok[id] && claimed[goals[id]] == id {
+			agents[id].Goal = goals[id]
+		}
+	}
+	for id := range agents {
+		if ok[id] && claimed[goals[id]] != id {
+			agents[id].Goal = p.staging(id, goals[id], agents)
+		}
+	}
+	if s.AgentRepair != nil {
+		for id := range agents {
+			agents[id].Delay = s.AgentRepair[id]
+		}
+	}
+	var paths [][]pos.Pos
+	var solved bool
+	switch p.Solver {
+	case "PP":
+		paths, solved = Prioritized(agents, p.order(s, agents), s.Turn, env)
+	case "PBS":
+		paths, solved = PBS(agents, s.Turn, env.MAPF.MaxNodes, env.MAPF.Horizon, env)
+	default:
+		paths, solved = Solve(agents, s.Turn, p.Weight, env.MAPF.MaxNodes, env.MAPF.Horizon, env)
+	}
+	if !solved {
+		p.Stats.Failures++
+	}
+	//経路が見つからないエージェントがいるならその場にとどまる
+	if paths == nil {
+		paths = make([][]pos.Pos, env.NumAgents)
+		for id := range paths {
+			paths[id] = []pos.Pos{s.AgentPos[id]}
+		}
+	}
+	p.record(reason, s.Turn, paths)
+	p.Paths = paths
+	p.Goals = append([]pos.Pos{}, goals...)
+	p.HasGoal = append([]bool{}, ok...)
+	p.start = s.Turn
+}
+
+//order PPでのエージェントの優先順位（添字を優先順位の高い順に並べたもの）を返す
+//（DISTANCEとLOADでは, 目的地のないエージェントを最後にする）
+func (p *Planner) order(s *state.State, agents []Agent) []int {
+	n := len(agents)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if p.env.MAPF.Priority == "ROTATING" {
+		for i := range order {
+			order[i] = (s.Turn + i) % n
+		}
+		return order
+	}
+	dist := make([]int, n)
+	for a, agent := range agents {
+		dist[a], _ = p.env.Dist(agent.ID, agent.Start, agent.Goal)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if (dist[a] == 0) != (dist[b] == 0) {
+			return dist[a] != 0
+		}
+		if p.env.MAPF.Priority == "LOAD" && len(s.AgentItems[a]) != len(s.AgentItems[b]) {
+			return len(s.AgentItems[a]) > len(s.AgentItems[b])
+		}
+		return dist[a] < dist[b]
+	})
+	return order
+}
+
+//staging あるエージェントが目的地の順番を待つマスを返す
+//（目的地から近い順に, 他のエージェントのゴールでなく, デポでも停止禁止でもないマスを選ぶ. なければ現在の座標）
+func (p *Planner) staging(id int, goal pos.Pos, agents []Agent) pos.Pos {
+	env := p.env
+	taken := make(map[pos.Pos]bool)
+	for other, agent := range agents {
+		if other != id {
+			taken[agent.Goal] = true
+		}
+	}
+	best, bestDist := agents[id].Start, -1
+	for _, c := range env.AllPos {
+		if taken[c] || env.IsDepot(c) || env.IsNoStop(c) || !env.CanEnter(id, c) {
+			continue
+		}
+		d, reachable := env.Dist(id, c, goal)
+		if _, fromStart := env.Dist(id, agents[id].Start, c); !reachable || !fromStart {
+			continue
+		}
+		if bestDist == -1 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+//needReplan 計画し直す理由を返す（計画し直す必要がなければnoReplan）
+func (p *Planner) needReplan(s *state.State, goals []pos.Pos, ok []bool, appeared []pos.Pos) int {
+	if p.Paths == nil {
+		return replanFirst
+	}
+	for id := range p.Paths {
+		if at(p.Paths[id], s.Turn-p.start) != s.AgentPos[id] {
+			return replanFailure
+		}
+	}
+	if len(appeared) > 0 {
+		return replanItem
+	}
+	for id := range p.Paths {
+		if ok[id] != p.HasGoal[id] || (ok[id] && goals[id] != p.Goals[id]) {
+			return replanGoal
+		}
+	}
+	executed := s.Turn - p.start
+	if (p.env.MAPF.ReplanEvery > 0 && executed >= p.env.MAPF.ReplanEvery) || (p.env.MAPF.Horizon > 0 && executed >= p.env.MAPF.Horizon) {
+		return replanPeriodic
+	}
+	return noReplan
+}
+
+//record 計画し直した理由と, 確定していた経路がどれだけ変わったかを記録する
+func (p *Planner) record(reason int, turn int, paths [][]pos.Pos) {
+	p.Stats.Replans++
+	switch reason {
+	case replanFirst:
+		return
+	case replanPeriodic:
+		p.Stats.Periodic++
+	case replanItem:
+		p.Stats.OnItem++
+	case replanGoal:
+		p.Stats.OnGoal++
+	case replanFailure:
+		p.Stats.OnFailure++
+	}
+	p.Stats.Executed += turn - p.start
+	//確定していた経路のうち, まだ実行していない部分を比べる
+	end := p.start + len(p.Paths[0]) - 1
+	for _, path := range p.Paths {
+		if p.start+len(path)-1 > end {
+			end = p.start + len(path) - 1
+		}
+	}
+	if p.env.MAPF.Horizon > 0 && p.start+p.env.MAPF.Horizon < end {
+		end = p.start + p.env.MAPF.Horizon
+	}
+	for id, path := range p.Paths {
+		changed := false
+		for t := turn + 1; t <= end; t++ {
+			if at(path, t-p.start) != at(paths[id], t-turn) {
+				p.Stats.ChangedSteps++
+				changed = true
+			}
+		}
+		if changed {
+			p.Stats.ChangedAgents++
+		}
+	}
+}
+
+//Action 計画した経路に従ってあるエージェントの行動を返す
+//（目的地にとどまる予定なら, デポではアイテムを置き, アイテムがあれば拾う）
+func (p *Planner) Action(id int, s *state.State) int {
+	now := s.AgentPos[id]
+	nxt := at(p.Paths[id], s.Turn-p.start+1)
+	if nxt != now {
+		return FirstAction([]pos.Pos{now, nxt}, p.env)
+	}
+	if !p.HasGoal[id] || now != p.Goals[id] {
+		return action.STAY
+	}
+	if p.env.IsDepot(now) {
+		return action.CLEAR
+	}
+	if len(s.PosItems[now]) > 0 && len(s.AgentItems[id]) < p.env.Capacity(id) {
+		return action.PICKUP
+	}
+	return action.STAY
+}
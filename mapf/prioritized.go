@@ -0,0 +1,165 @@
+package mapf
+
+import (
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//Prioritized 優先順位の高い順に1人ずつ, それまでに計画したエージェントの経路を予約表で避けながら経路を求める（Prioritized Planning）
+//（orderはエージェントの添字を優先順位の高い順に並べたもの. 経路が見つからないエージェントがいれば, そのエージェントを先頭にしてやり直す.
+//エージェントの数だけやり直しても見つからなければ, 見つからないエージェントはその場にとどまり, falseを返す）
+func Prioritized(agents []Agent, order []int, turn int, env *env.Env) ([][]pos.Pos, bool) {
+	order = append([]int{}, order...)
+	var paths [][]pos.Pos
+	for retry := 0; retry <= len(order); retry++ {
+		var failed int
+		paths, failed = prioritized(agents, order, turn, env)
+		if failed == -1 {
+			return paths, true
+		}
+		for i, a := range order {
+			if a == failed {
+				copy(order[1:i+1], order[:i])
+				order[0] = failed
+				break
+			}
+		}
+	}
+	return paths, false
+}
+
+//prioritized 与えられた優先順位で経路を求め, 経路が見つからなかった最初のエージェントを返す（全員見つかれば-1）
+func prioritized(agents []Agent, order []int, turn int, env *env.Env) ([][]pos.Pos, int) {
+	table := NewTable()
+	table.NoFollow = true
+	paths := make([][]pos.Pos, len(agents))
+	failed := -1
+	for _, a := range order {
+		path, _, ok := lowLevel(agents[a], table, newOccupancy(-1, nil), turn, 1, env)
+		if !ok {
+			path = []pos.Pos{agents[a].Start}
+			if failed == -1 {
+				failed = a
+			}
+		}
+		paths[a] = path
+		table.ReserveForever(agents[a].ID, path)
+	}
+	return paths, failed
+}
+
+//pbsNode PBSの探索木の節点
+type pbsNode struct {
+	higher    [][]bool //higher[a][b]が真ならaはbより優先順位が高い（推移的に閉じていない）
+	paths     [][]pos.Pos
+	cost      int
+	conflicts int
+	first     conflict
+}
+
+//ancestors あるエージェントより（推移的に）優先順位が高いエージェントの集合を返す
+func (n *pbsNode) ancestors(a int) map[int]bool {
+	ret := make(map[int]bool)
+	stack := []int{a}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for b := range n.higher {
+			if n.higher[b][cur] && !ret[b] {
+				ret[b] = true
+				stack = append(stack, b)
+			}
+		}
+	}
+	return ret
+}
+
+//replan あるエージェントと, それより（推移的に）優先順位が低いエージェントの経路を, 優先順位の高い順に計画し直す（経路が見つからなければfalse）
+func (n *pbsNode) replan(a int, agents []Agent, turn int, window int, env *env.Env) bool {
+	targets := []int{}
+	anc := make([]map[int]bool, len(agents))
+	for b := range agents {
+		anc[b] = n.ancestors(b)
+		if b == a || anc[b][a] {
+			targets = append(targets, b)
+		}
+	}
+	//優先順位が高いエージェントほど, それより高いエージェントが少ない
+	sort.SliceStable(targets, func(i, j int) bool {
+		return len(anc[targets[i]]) < len(anc[targets[j]])
+	})
+	for _, b := range targets {
+		table := NewTable()
+		table.NoFollow = true
+		for c := range anc[b] {
+			table.ReserveForever(agents[c].ID, n.paths[c])
+		}
+		path, _, ok := lowLevel(agents[b], table, newOccupancy(b, n.paths), turn, 1, env)
+		if !ok {
+			return false
+		}
+		n.paths[b] = path
+	}
+	n.cost = 0
+	for _, path := range n.paths {
+		n.cost += len(path) - 1
+	}
+	n.first, n.conflicts, _ = findConflicts(n.paths, window)
+	return true
+}
+
+//PBS 衝突する2つのエージェントの間の優先順位を深さ優先で決めていき, 衝突しない経路を求める（Priority-Based Search）
+//（windowが0でなければ時刻windowまでの衝突だけを解消する. 節点をmaxNodes個展開しても見つからなければ, それまでで衝突が最も少なかった経路とfalseを返す）
+func PBS(agents []Agent, turn int, maxNodes int, window int, env *env.Env) ([][]pos.Pos, bool) {
+	root := &pbsNode{higher: make([][]bool, len(agents)), paths: make([][]pos.Pos, len(agents))}
+	for a := range agents {
+		root.higher[a] = make([]bool, len(agents))
+		path, _, ok := lowLevel(agents[a], NewTable(), newOccupancy(a, root.paths), turn, 1, env)
+		if !ok {
+			return nil, false
+		}
+		root.paths[a] = path
+	}
+	for _, path := range root.paths {
+		root.cost += len(path) - 1
+	}
+	root.first, root.conflicts, _ = findConflicts(root.paths, window)
+	stack := []*pbsNode{root}
+	best := root
+	for expanded := 0; len(stack) > 0 && expanded < maxNodes; expanded++ {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur.conflicts == 0 {
+			return cur.paths, true
+		}
+		children := []*pbsNode{}
+		a, b := cur.first.A.Agent, cur.first.B.Agent
+		for _, pair := range [][2]int{{a, b}, {b, a}} {
+			hi, lo := pair[0], pair[1]
+			//すでに逆の優先順位が決まっているならダメ
+			if cur.ancestors(hi)[lo] {
+				continue
+			}
+			child := &pbsNode{higher: make([][]bool, len(agents)), paths: append([][]pos.Pos{}, cur.paths...)}
+			for c := range cur.higher {
+				child.higher[c] = append([]bool{}, cur.higher[c]...)
+			}
+			child.higher[hi][lo] = true
+			if !child.replan(lo, agents, turn, window, env) {
+				continue
+			}
+			if child.conflicts < best.conflicts {
+				best = child
+			}
+			children = append(children, child)
+		}
+		//コストが小さい子を先に展開する
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].cost > children[j].cost
+		})
+		stack = append(stack, children...)
+	}
+	return best.paths, false
+}
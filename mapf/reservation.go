@@ -0,0 +1,124 @@
+package mapf
+
+import "github.com/Div9851/warehouse-sim/pos"
+
+//vertex 時刻tにある座標にいること
+type vertex struct {
+	Pos pos.Pos
+	T   int
+}
+
+//edge 時刻tから時刻t+1にかけてある座標から別の座標に移動すること
+type edge struct {
+	From pos.Pos
+	To   pos.Pos
+	T    int
+}
+
+//hold ある時刻からずっとある座標にとどまること
+type hold struct {
+	ID   int
+	From int
+}
+
+//Table 時空間の予約表（時刻は計画を始めたターンを0とする相対的なもの）
+type Table struct {
+	vertices map[vertex]int
+	edges    map[edge]int
+	holds    map[pos.Pos]hold
+	latest   map[pos.Pos]int //各座標が予約されている最も遅い時刻
+	maxT     int             //予約されている最も遅い時刻
+	NoFollow bool            //真なら, 他のエージェントが出ていくマスに同じ時刻に入る（後ろについていく）移動もできない
+}
+
+//NewTable 空の予約表を返す
+func NewTable() *Table {
+	return &Table{vertices: make(map[vertex]int), edges: make(map[edge]int), holds: make(map[pos.Pos]hold), latest: make(map[pos.Pos]int)}
+}
+
+//Reserve あるエージェントのために時刻tのある座標を予約する（すでに予約されていれば先の予約を優先する）
+func (table *Table) Reserve(id int, p pos.Pos, t int) {
+	if _, exist := table.vertices[vertex{Pos: p, T: t}]; !exist {
+		table.vertices[vertex{Pos: p, T: t}] = id
+	}
+	if last, exist := table.latest[p]; !exist || t > last {
+		table.latest[p] = t
+	}
+	if t > table.maxT {
+		table.maxT = t
+	}
+}
+
+//ReservePath あるエージェントの経路（時刻0から順に並べた座標）を予約し, 最後の座標を時刻windowまで予約する
+func (table *Table) ReservePath(id int, path []pos.Pos, window int) {
+	for t, p := range path {
+		table.Reserve(id, p, t)
+		if t+1 < len(path) {
+			table.edges[edge{From: p, To: path[t+1], T: t}] = id
+		}
+	}
+	last := path[len(path)-1]
+	for t := len(path); t <= window; t++ {
+		table.Reserve(id, last, t)
+	}
+}
+
+//ReserveForever あるエージェントの経路を予約し, 最後の座標をその後ずっと予約する
+func (table *Table) ReserveForever(id int, path []pos.Pos) {
+	table.ReservePath(id, path, 0)
+	if _, exist := table.holds[path[len(path)-1]]; !exist {
+		table.holds[path[len(path)-1]] = hold{ID: id, From: len(path) - 1}
+	}
+}
+
+//forbidMove 時刻t-1から時刻tにかけてfromからtoに移動することを禁止する（CBSの制約に使う）
+func (table *Table) forbidMove(from pos.Pos, to pos.Pos, t int) {
+	table.edges[edge{From: to, To: from, T: t - 1}] = -1
+	if t > table.maxT {
+		table.maxT = t
+	}
+}
+
+//IsFree あるエージェントが時刻tから時刻t+1にかけてある座標から別の座標に移動（またはとどまる）できるかどうかを返す
+//（移動先が他のエージェントに予約されている場合と, 他のエージェントとすれ違う場合はできない）
+func (table *Table) IsFree(id int, from pos.Pos, to pos.Pos, t int) bool {
+	if other, exist := table.vertices[vertex{Pos: to, T: t + 1}]; exist && other != id {
+		return false
+	}
+	if h, exist := table.holds[to]; exist && h.ID != id && h.From <= t+1 {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	if other, exist := table.edges[edge{From: to, To: from, T: t}]; exist && other != id {
+		return false
+	}
+	if other, exist := table.vertices[vertex{Pos: to, T: t}]; table.NoFollow && exist && other != id {
+		return false
+	}
+	return true
+}
+
+//ReservedBy 時刻tにある座標を予約しているエージェントを返す（予約されていなければfalse）
+func (table *Table) ReservedBy(p pos.Pos, t int) (int, bool) {
+	id, exist := table.vertices[vertex{Pos: p, T: t}]
+	return id, exist
+}
+
+//canStayFrom あるエージェントが時刻tからずっとある座標にとどまり続けられるかどうかを返す
+func (table *Table) canStayFrom(id int, p pos.Pos, t int) bool {
+	if h, exist := table.holds[p]; exist && h.ID != id {
+		return false
+	}
+	last, exist := table.latest[p]
+	if !exist || last < t {
+		return true
+	}
+	for k := t; k <= last; k++ {
+		if other, exist := table.vertices[vertex{Pos: p, T: k}]; exist && other != id {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package mapf
+
+import (
+	"container/heap"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//node 時空間のA*の探索の節点
+type node struct {
+	Pos    pos.Pos
+	T      int
+	F      int //T+（ゴールまでの最短距離）
+	H      int //ゴールまでの最短距離
+	Parent int //親の節点の添字（始点は-1）
+}
+
+//openList 未展開の節点の添字の優先度付きキュー（Fが小さく, 同じならHが小さい順）
+type openList struct {
+	nodes *[]node
+	idx   []int
+}
+
+func (o openList) Len() int {
+	return len(o.idx)
+}
+
+func (o openList) Less(i, j int) bool {
+	a, b := (*o.nodes)[o.idx[i]], (*o.nodes)[o.idx[j]]
+	if a.F != b.F {
+		return a.F < b.F
+	}
+	return a.H < b.H
+}
+
+func (o openList) Swap(i, j int) {
+	o.idx[i], o.idx[j] = o.idx[j], o.idx[i]
+}
+
+func (o *openList) Push(x interface{}) {
+	o.idx = append(o.idx, x.(int))
+}
+
+func (o *openList) Pop() interface{} {
+	x := o.idx[len(o.idx)-1]
+	o.idx = o.idx[:len(o.idx)-1]
+	return x
+}
+
+//PlanWindowed あるエージェントが現在の座標からゴールに向かう経路を, 予約表を避けながら時刻windowまで時空間のA*で求める
+//（経路は時刻0から順に並べた座標で, windowまでにゴールに着かなければ残りの最短距離が最も短くなる経路を返す. 経路がなければfalse）
+//（turnは現在のターンで, 何ターンに1回移動できるかの制限に使う）
+func PlanWindowed(id int, start pos.Pos, goal pos.Pos, turn int, window int, table *Table, env *env.Env) ([]pos.Pos, bool) {
+	h0, reachable := env.Dist(id, start, goal)
+	if !reachable {
+		return nil, false
+	}
+	nodes := []node{{Pos: start, T: 0, F: h0, H: h0, Parent: -1}}
+	open := &openList{nodes: &nodes, idx: []int{0}}
+	closed := make(map[vertex]bool)
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(int)
+		n := nodes[cur]
+		if n.Pos == goal || n.T == window {
+			path := make([]pos.Pos, n.T+1)
+			for k := cur; k != -1; k = nodes[k].Parent {
+				path[nodes[k].T] = nodes[k].Pos
+			}
+			return path, true
+		}
+		if closed[vertex{Pos: n.Pos, T: n.T}] {
+			continue
+		}
+		closed[vertex{Pos: n.Pos, T: n.T}] = true
+		nexts := []pos.Pos{}
+		//停止禁止のマスではとどまれない
+		if !env.IsNoStop(n.Pos) {
+			nexts = append(nexts, n.Pos)
+		}
+		for _, move := range env.AgentMoves(id, n.Pos, turn+n.T) {
+			nexts = append(nexts, pos.NextPos(n.Pos, move, env.MapData))
+		}
+		for _, nxt := range nexts {
+			if closed[vertex{Pos: nxt, T: n.T + 1}] || !table.IsFree(id, n.Pos, nxt, n.T) {
+				continue
+			}
+			h, reachable := env.Dist(id, nxt, goal)
+			if !reachable {
+				continue
+			}
+			nodes = append(nodes, node{Pos: nxt, T: n.T + 1, F: n.T + 1 + h, H: h, Parent: cur})
+			heap.Push(open, len(nodes)-1)
+		}
+	}
+	return nil, false
+}
+
+//FirstAction 経路の最初の1ステップに対応する行動を返す
+func FirstAction(path []pos.Pos, env *env.Env) int {
+	if len(path) < 2 || path[0] == path[1] {
+		return action.STAY
+	}
+	for _, move := range []int{action.UP, action.DOWN, action.LEFT, action.RIGHT} {
+		if pos.NextPos(path[0], move, env.MapData) == path[1] {
+			return move
+		}
+	}
+	return action.STAY
+}
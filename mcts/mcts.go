@@ -37,6 +37,47 @@ func makeTuple(id int, score float64) tuple {
 	return tuple{ID: id, Score: score}
 }
 
+//getValidActions ある状態でエージェントが選択できる行動のリストを返す
+func getValidActions(id int, s *state.State, env *env.Env) []int {
+	now := s.AgentPos[id]
+	//電池が切れたら動けない
+	if s.AgentBattery != nil && s.AgentBattery[id] == 0 {
+		return []int{action.STAY}
+	}
+	if s.AgentRepair != nil && s.AgentRepair[id] > 0 {
+		return []int{action.STAY}
+	}
+	moves := env.AgentMoves(id, now, s.Turn)
+	validActions := make([]int, len(moves))
+	copy(validActions, moves)
+	if len(s.PosItems[now]) > 0 && len(s.AgentItems[id]) < env.Capacity(id) {
+		validActions = append(validActions, action.PICKUP)
+	}
+	if env.NumAccepted(now, s.AgentItems[id]) > 0 {
+		validActions = append(validActions, action.CLEAR)
+	}
+	//充電ステーションにいるならSTAYで充電できる
+	if s.AgentBattery != nil && env.IsCharger(now) && s.AgentBattery[id] < env.Battery.Capacity {
+		validActions = append(validActions, action.STAY)
+	}
+	if len(validActions) == 0 {
+		validActions = append(validActions, action.STAY)
+	}
+	return validActions
+}
+
+//rolloutPolicy ロールアウトと他のエージェントの行動に使う方策で, 全てのエージェントの行動を決定する
+func rolloutPolicy(s *state.State, env *env.Env, rnd *rand.Rand) []int {
+	var actions []int
+	switch env.RolloutPolicy {
+	case "HUNGARIAN":
+		actions, _ = greedy.Hungarian(s, env, rnd, env.GreedyCA)
+	default:
+		actions, _ = greedy.Greedy(s, env, rnd, env.GreedyCA)
+	}
+	return actions
+}
+
 //MCTS モンテカルロ木探索で行動を決定する
 func MCTS(id int, startState *state.State, env *env.Env, rnd *rand.Rand, coef float64) int {
 	states := []*state.State{startState}
@@ -68,7 +109,7 @@ func MCTS(id int, startState *state.State, env *env.Env, rnd *rand.Rand, coef fl
 			//roll out
 			now := states[stateID]
 			for now.Turn < env.LastTurn && depth < env.MaxDepth {
-				actions, _ := greedy.Greedy(now, env, rnd, env.GreedyCA)
+				actions := rolloutPolicy(now, env, rnd)
 				nxt, _, _, rewards := state.NextState(now, actions, env, rnd)
 				now = nxt
 				r += k * rewards[id]
@@ -77,14 +118,7 @@ func MCTS(id int, startState *state.State, env *env.Env, rnd *rand.Rand, coef fl
 			}
 			return r
 		}
-		validActions := make([]int, len(env.ValidMoves[states[stateID].AgentPos[id]]))
-		copy(validActions, env.ValidMoves[states[stateID].AgentPos[id]])
-		if states[stateID].PosItems[states[stateID].AgentPos[id]] > 0 && states[stateID].AgentItems[id] < env.MaxItems {
-			validActions = append(validActions, action.PICKUP)
-		}
-		if states[stateID].AgentPos[id] == env.DepotPos && states[stateID].AgentItems[id] > 0 {
-			validActions = append(validActions, action.CLEAR)
-		}
+		validActions := getValidActions(id, states[stateID], env)
 		var bestScore float64
 		var bestActions []int
 		for _, act := range validActions {
@@ -110,7 +144,7 @@ func MCTS(id int, startState *state.State, env *env.Env, rnd *rand.Rand, coef fl
 		if len(childs[stateID][chosen]) == env.MaxChilds {
 			to = childs[stateID][chosen][rnd.Intn(len(childs[stateID][chosen]))]
 		} else {
-			actions, _ := greedy.Greedy(states[stateID], env, rnd, env.GreedyCA)
+			actions := rolloutPolicy(states[stateID], env, rnd)
 			actions[id] = chosen
 			nxt, _, _, rewards := state.NextState(states[stateID], actions, env, rnd)
 			to = len(states)
@@ -134,14 +168,7 @@ func MCTS(id int, startState *state.State, env *env.Env, rnd *rand.Rand, coef fl
 		dfs(0, 1)
 	}
 	ts := make(tuples, 0)
-	validActions := make([]int, len(env.ValidMoves[states[0].AgentPos[id]]))
-	copy(validActions, env.ValidMoves[states[0].AgentPos[id]])
-	if states[0].PosItems[states[0].AgentPos[id]] > 0 && states[0].AgentItems[id] < env.MaxItems {
-		validActions = append(validActions, action.PICKUP)
-	}
-	if states[0].AgentPos[id] == env.DepotPos && states[0].AgentItems[id] > 0 {
-		validActions = append(validActions, action.CLEAR)
-	}
+	validActions := getValidActions(id, states[0], env)
 	greedyActions, values := greedy.Greedy(startState, env, rnd, false)
 	for _, act := range validActions {
 		var score float64
@@ -0,0 +1,133 @@
+package observe
+
+import (
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Belief あるエージェントがこれまでの観測から推定している世界の状態
+type Belief struct {
+	ID          int
+	PosItems    map[pos.Pos][]item.Item //床に置かれていると思っているアイテム
+	SeenAt      map[pos.Pos]int         //PosItemsの各座標を最後に観測したターン
+	AgentPos    []pos.Pos               //各エージェントがいると思っている座標
+	AgentItems  [][]item.Item           //各エージェントが持っていると思っているアイテム
+	AgentSeenAt []int                   //各エージェントを最後に観測したターン
+}
+
+//New エージェントのIDと初期状態を受け取り, 初期状態を観測したBeliefを返す
+//（他のエージェントの初期位置は全員が知っているものとする）
+func New(id int, s *state.State, env *env.Env) *Belief {
+	b := &Belief{
+		ID:          id,
+		PosItems:    make(map[pos.Pos][]item.Item),
+		SeenAt:      make(map[pos.Pos]int),
+		AgentPos:    make([]pos.Pos, env.NumAgents),
+		AgentItems:  make([][]item.Item, env.NumAgents),
+		AgentSeenAt: make([]int, env.NumAgents),
+	}
+	copy(b.AgentPos, s.AgentPos)
+	copy(b.AgentItems, s.AgentItems)
+	for i := range b.AgentSeenAt {
+		b.AgentSeenAt[i] = s.Turn
+	}
+	b.Update(s, env)
+	return b
+}
+
+//Clone 複製したBeliefを返す（アイテムのスライスは書き換えられないので共有する）
+func (b *Belief) Clone() *Belief {
+	c := &Belief{
+		ID:          b.ID,
+		PosItems:    make(map[pos.Pos][]item.Item, len(b.PosItems)),
+		SeenAt:      make(map[pos.Pos]int, len(b.SeenAt)),
+		AgentPos:    append([]pos.Pos{}, b.AgentPos...),
+		AgentItems:  append([][]item.Item{}, b.AgentItems...),
+		AgentSeenAt: append([]int{}, b.AgentSeenAt...),
+	}
+	for p, items := range b.PosItems {
+		c.PosItems[p] = items
+	}
+	for p, t := range b.SeenAt {
+		c.SeenAt[p] = t
+	}
+	return c
+}
+
+//Update 真の状態のうち, 現在位置から観測できる部分でBeliefを更新する
+func (b *Belief) Update(s *state.State, env *env.Env) {
+	now := s.AgentPos[b.ID]
+	//観測できる範囲のマスのアイテムを見直す
+	r := env.Sensing.Radius
+	if !env.IsPartiallyObservable() {
+		r = env.MapDataH + env.MapDataW
+	}
+	for y := now.Y - r; y <= now.Y+r; y++ {
+		for x := now.X - r; x <= now.X+r; x++ {
+			if 0 > x || x >= env.MapDataW || 0 > y || y >= env.MapDataH {
+				continue
+			}
+			p := pos.New(x, y)
+			if !env.Visible(now, p) {
+				continue
+			}
+			if items, exist := s.PosItems[p]; exist {
+				b.PosItems[p] = items
+				b.SeenAt[p] = s.Turn
+			} else {
+				delete(b.PosItems, p)
+				delete(b.SeenAt, p)
+			}
+		}
+	}
+	//長い間観測していないアイテムは忘れる
+	if env.Sensing.Memory > 0 {
+		for p, t := range b.SeenAt {
+			if s.Turn-t > env.Sensing.Memory {
+				delete(b.PosItems, p)
+				delete(b.SeenAt, p)
+			}
+		}
+	}
+	for id, p := range s.AgentPos {
+		if id == b.ID || env.Visible(now, p) {
+			b.AgentPos[id] = p
+			b.AgentItems[id] = s.AgentItems[id]
+			b.AgentSeenAt[id] = s.Turn
+		}
+	}
+}
+
+//View 真の状態のうち, エージェントの位置とアイテムをBeliefに置き換えた状態を返す
+//（ターン, 自分自身の状態, 電池や故障などのエージェントの状態は真の状態のものを使う）
+func (b *Belief) View(s *state.State) *state.State {
+	view := *s
+	view.AgentPos = make([]pos.Pos, len(b.AgentPos))
+	copy(view.AgentPos, b.AgentPos)
+	view.AgentItems = make([][]item.Item, len(b.AgentItems))
+	copy(view.AgentItems, b.AgentItems)
+	view.PosItems = make(map[pos.Pos][]item.Item)
+	for p, items := range b.PosItems {
+		view.PosItems[p] = items
+	}
+	return &view
+}
+
+//NumKnownItems 真の状態で床に置かれているアイテムのうち, 置かれている座標を正しく知っているものの数を返す
+func (b *Belief) NumKnownItems(s *state.State) int {
+	cnt := 0
+	for p, items := range s.PosItems {
+		known := make(map[int]bool)
+		for _, it := range b.PosItems[p] {
+			known[it.ID] = true
+		}
+		for _, it := range items {
+			if known[it.ID] {
+				cnt++
+			}
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,44 @@
+package observe
+
+import (
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+func TestUpdate(t *testing.T) {
+	e, err := env.Load("../env/testdata/typed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Sensing = env.Sensing{Radius: 2}
+	agentPos := []pos.Pos{pos.New(0, 0), pos.New(6, 6)}
+	posItems := map[pos.Pos][]item.Item{
+		pos.New(1, 0): {item.New(0, 0, 1, 0)},
+		pos.New(6, 3): {item.New(1, 0, 1, 0)},
+	}
+	s := state.New(1, make([][]item.Item, 2), agentPos, posItems, nil, make([]bool, 2))
+	b := New(0, s, e)
+	if len(b.PosItems) != 1 || len(b.PosItems[pos.New(1, 0)]) != 1 {
+		t.Fatalf("agent 0 should see only the item at (1, 0), but `%v`", b.PosItems)
+	}
+	//見えるところで拾われたアイテムは忘れ, 見えないエージェントは最後に見た位置にいると思い続ける
+	nxt := state.New(2, make([][]item.Item, 2), []pos.Pos{pos.New(0, 0), pos.New(5, 6)}, map[pos.Pos][]item.Item{}, nil, make([]bool, 2))
+	b.Update(nxt, e)
+	if len(b.PosItems) != 0 {
+		t.Fatalf("agent 0 should know the item at (1, 0) is gone, but `%v`", b.PosItems)
+	}
+	if b.AgentPos[1] != pos.New(6, 6) {
+		t.Fatalf("agent 0 should believe agent 1 is still at (6, 6), but `%v`", b.AgentPos[1])
+	}
+	view := b.View(nxt)
+	if view.AgentPos[0] != pos.New(0, 0) || view.AgentPos[1] != pos.New(6, 6) {
+		t.Fatalf("view.AgentPos should be `[(0, 0) (6, 6)]`, but `%v`", view.AgentPos)
+	}
+	if b.NumKnownItems(s) != 0 {
+		t.Fatalf("agent 0 should no longer know any item of the first state, but `%v`", b.NumKnownItems(s))
+	}
+}
@@ -15,7 +15,24 @@ func New(x int, y int) Pos {
 	return Pos{X: x, Y: y}
 }
 
+//Abs 整数の絶対値を返す
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+//against ある行動で逆走になる一方通行の記号
+var against = map[int]byte{
+	action.UP:    'v',
+	action.DOWN:  '^',
+	action.LEFT:  '>',
+	action.RIGHT: '<',
+}
+
 //NextPos 現在の座標, 行動, マップデータを受け取り, 次の座標を返す
+//（一方通行のマスから逆向きに出る移動, 一方通行のマスに逆向きに入る移動はできない）
 func NextPos(pos Pos, act int, mapData []string) Pos {
 	H, W := len(mapData), len(mapData[0])
 	nx, ny := pos.X, pos.Y
@@ -32,5 +49,8 @@ func NextPos(pos Pos, act int, mapData []string) Pos {
 	if 0 > nx || nx >= W || 0 > ny || ny >= H || mapData[ny][nx] == '#' {
 		nx, ny = pos.X, pos.Y
 	}
+	if c, exist := against[act]; exist && (mapData[pos.Y][pos.X] == c || mapData[ny][nx] == c) {
+		nx, ny = pos.X, pos.Y
+	}
 	return New(nx, ny)
 }
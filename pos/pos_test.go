@@ -1,6 +1,10 @@
 package pos
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/action"
+)
 
 func TestPos(t *testing.T) {
 	pos := New(1, 3)
@@ -10,4 +14,36 @@ func TestPos(t *testing.T) {
 	if pos.Y != 3 {
 		t.Fatalf("pos.Y should be `3`, but `%v`", pos.Y)
 	}
+	if Abs(-2) != 2 || Abs(2) != 2 {
+		t.Fatalf("Abs(-2) and Abs(2) should be `2`, but `%v` and `%v`", Abs(-2), Abs(2))
+	}
+}
+
+func TestNextPos(t *testing.T) {
+	mapData := []string{
+		"...",
+		".^#",
+		"...",
+	}
+	var nxt Pos
+	nxt = NextPos(New(1, 2), action.UP, mapData)
+	if nxt != New(1, 1) {
+		t.Fatalf("NextPos((1, 2), UP) should be `(1, 1)`, but `(%v, %v)`", nxt.X, nxt.Y)
+	}
+	nxt = NextPos(New(1, 0), action.DOWN, mapData)
+	if nxt != New(1, 0) {
+		t.Fatalf("NextPos((1, 0), DOWN) should be `(1, 0)`, but `(%v, %v)`", nxt.X, nxt.Y)
+	}
+	nxt = NextPos(New(1, 1), action.DOWN, mapData)
+	if nxt != New(1, 1) {
+		t.Fatalf("NextPos((1, 1), DOWN) should be `(1, 1)`, but `(%v, %v)`", nxt.X, nxt.Y)
+	}
+	nxt = NextPos(New(1, 1), action.LEFT, mapData)
+	if nxt != New(0, 1) {
+		t.Fatalf("NextPos((1, 1), LEFT) should be `(0, 1)`, but `(%v, %v)`", nxt.X, nxt.Y)
+	}
+	nxt = NextPos(New(1, 1), action.RIGHT, mapData)
+	if nxt != New(1, 1) {
+		t.Fatalf("NextPos((1, 1), RIGHT) should be `(1, 1)`, but `(%v, %v)`", nxt.X, nxt.Y)
+	}
 }
@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/state"
+	"github.com/Div9851/warehouse-sim/wire"
+)
+
+//Request 外部のコントローラに送る要求
+type Request struct {
+	Turn        int               `json:"turn"`
+	Agents      []int             `json:"agents"` //行動を決めてほしいエージェント
+	Observation *wire.Observation `json:"observation"`
+}
+
+//Reply 外部のコントローラからの応答
+type Reply struct {
+	Turn    int   `json:"turn"`    //応答した要求のターン（サブプロセスでは, 時間切れになった要求への遅れた応答を捨てるのに使う）
+	Actions []int `json:"actions"` //Agentsと同じ順に並べた行動
+}
+
+//Stats 外部のコントローラとのやり取りの集計
+type Stats struct {
+	Requests  int `json:"requests"`  //送った要求の数
+	Timeouts  int `json:"timeouts"`  //時間内に応答がなかった要求の数
+	Errors    int `json:"errors"`    //送受信や応答の解釈に失敗した要求の数
+	Fallbacks int `json:"fallbacks"` //有効な行動が返ってこなかったので代わりの方法で行動を決めた（エージェント, ターン）の数
+}
+
+//errTimeout 時間内に応答がなかったことを表すエラー
+var errTimeout = errors.New("timeout")
+
+//transport 要求を送って応答を受け取る方法
+type transport interface {
+	roundTrip(req Request, timeout time.Duration) (Reply, error)
+	close() error
+}
+
+//Controller 外部のコントローラ（サブプロセスまたはlocalhostのHTTPサーバー）にREMOTEのエージェントの行動を決めてもらう
+type Controller struct {
+	env     *env.Env
+	t       transport
+	timeout time.Duration
+	Stats   Stats
+}
+
+//New 環境設定を受け取り, 外部のコントローラに接続する（サブプロセスならここで起動する）
+func New(env *env.Env) (*Controller, error) {
+	var t transport
+	if len(env.Remote.Command) > 0 {
+		p, err := startProcess(env.Remote.Command, env.Remote.Dir)
+		if err != nil {
+			return nil, err
+		}
+		t = p
+	} else {
+		t = newHTTPClient(env.Remote.URL)
+	}
+	return &Controller{env: env, t: t, timeout: time.Duration(env.Remote.TimeoutMS) * time.Millisecond}, nil
+}
+
+//Actions 状態と行動を決めてほしいエージェントを受け取り, 各エージェントの行動と, それが有効かどうかを返す（添字はエージェントの番号）
+//（時間内に応答がない, または行動が範囲外のエージェントは無効になるので, 呼び出し側で代わりの方法で行動を決める）
+func (c *Controller) Actions(s *state.State, agents []int) ([]int, []bool) {
+	actions := make([]int, c.env.NumAgents)
+	ok := make([]bool, c.env.NumAgents)
+	c.Stats.Requests++
+	reply, err := c.t.roundTrip(Request{Turn: s.Turn, Agents: agents, Observation: wire.NewObservation(s, c.env)}, c.timeout)
+	switch {
+	case err == errTimeout:
+		c.Stats.Timeouts++
+	case err != nil:
+		c.Stats.Errors++
+	case len(reply.Actions) != len(agents):
+		c.Stats.Errors++
+	default:
+		for k, id := range agents {
+			if act := reply.Actions[k]; 0 <= act && act < action.NUM {
+				actions[id], ok[id] = act, true
+			}
+		}
+	}
+	for _, id := range agents {
+		if !ok[id] {
+			c.Stats.Fallbacks++
+		}
+	}
+	return actions, ok
+}
+
+//Close 外部のコントローラとの接続を閉じる（サブプロセスなら終了させる. 何度呼んでもよく, 閉じた後の要求は失敗する）
+func (c *Controller) Close() error {
+	if err := c.t.close(); err != nil {
+		return fmt.Errorf("can't close remote controller (%s)", err)
+	}
+	return nil
+}
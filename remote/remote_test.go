@@ -0,0 +1,78 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+func TestHTTPController(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//ターン1にはエージェント0にLEFT, エージェント2に範囲外の行動を返し, ターン2には時間内に応答しない
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		if req.Turn == 2 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		json.NewEncoder(w).Encode(Reply{Turn: req.Turn, Actions: []int{action.LEFT, 99}})
+	}))
+	defer server.Close()
+	c := &Controller{env: e, t: newHTTPClient(server.URL), timeout: 50 * time.Millisecond}
+	s := state.New(1, make([][]item.Item, 3), []pos.Pos{pos.New(3, 3), pos.New(4, 3), pos.New(5, 3)}, map[pos.Pos][]item.Item{}, map[pos.Pos]float64{}, make([]bool, 3))
+	actions, ok := c.Actions(s, []int{0, 2})
+	if !ok[0] || actions[0] != action.LEFT || ok[1] || ok[2] {
+		t.Fatalf("agent 0 should move LEFT and agent 2 should fall back, but %v %v", actions, ok)
+	}
+	s.Turn = 2
+	if _, ok := c.Actions(s, []int{0, 2}); ok[0] || ok[2] {
+		t.Fatal("slow reply should time out")
+	}
+	want := Stats{Requests: 2, Timeouts: 1, Fallbacks: 3}
+	if c.Stats != want {
+		t.Fatalf("stats should be `%+v`, but `%+v`", want, c.Stats)
+	}
+}
+
+func TestProcessClose(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//catは要求をそのまま返すので, ターンは一致するが行動の数が合わない
+	p, err := startProcess([]string{"cat"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Controller{env: e, t: p, timeout: time.Second}
+	s := state.New(1, make([][]item.Item, 3), []pos.Pos{pos.New(3, 3), pos.New(4, 3), pos.New(5, 3)}, map[pos.Pos][]item.Item{}, map[pos.Pos]float64{}, make([]bool, 3))
+	c.Actions(s, []int{0})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("closing twice should do nothing, but `%v`", err)
+	}
+	//閉じた後の要求は失敗し, 代わりの方法で行動を決める
+	if _, ok := c.Actions(s, []int{0}); ok[0] {
+		t.Fatal("request after close should fail")
+	}
+	want := Stats{Requests: 2, Errors: 2, Fallbacks: 2}
+	if c.Stats != want {
+		t.Fatalf("stats should be `%+v`, but `%+v`", want, c.Stats)
+	}
+}
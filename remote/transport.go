@@ -0,0 +1,185 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+//process 標準入出力で1行ずつJSONをやり取りするサブプロセス
+type process struct {
+	cmd      *exec.Cmd
+	requests chan []byte //stdinに書き込む要求（書き込みが詰まってもシミュレーションが止まらないように別のゴルーチンで書く）
+	replies  chan []byte //stdoutから読んだ行（プロセスが終了すると閉じる）
+
+	mu     sync.Mutex //requestsに送ることと閉じることを排他する
+	closed bool
+}
+
+//startProcess コマンドをあるディレクトリで起動する
+func startProcess(command []string, dir string) (*process, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't open stdin of `%s` (%s)", command[0], err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't open stdout of `%s` (%s)", command[0], err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't start `%s` (%s)", command[0], err)
+	}
+	p := &process{cmd: cmd, requests: make(chan []byte, 1), replies: make(chan []byte, 16)}
+	go func() {
+		for b := range p.requests {
+			if _, err := stdin.Write(b); err != nil {
+				break
+			}
+		}
+		stdin.Close()
+		//残りの要求は捨てる
+		for range p.requests {
+		}
+	}()
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			p.replies <- append([]byte{}, scanner.Bytes()...)
+		}
+		close(p.replies)
+	}()
+	return p, nil
+}
+
+//roundTrip 要求を1行で書き込み, 同じターンへの応答が来るまで待つ
+func (p *process) roundTrip(req Request, timeout time.Duration) (Reply, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return Reply{}, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return Reply{}, fmt.Errorf("controller is closed")
+	}
+	select {
+	case p.requests <- append(b, '\n'):
+	case <-timer.C:
+		p.mu.Unlock()
+		return Reply{}, errTimeout
+	}
+	p.mu.Unlock()
+	for {
+		select {
+		case line, open := <-p.replies:
+			if !open {
+				return Reply{}, fmt.Errorf("controller exited")
+			}
+			var reply Reply
+			if err := json.Unmarshal(line, &reply); err != nil {
+				return Reply{}, fmt.Errorf("can't decode reply (%s)", err)
+			}
+			//時間切れになった要求への遅れた応答は捨てる
+			if reply.Turn != req.Turn {
+				continue
+			}
+			return reply, nil
+		case <-timer.C:
+			return Reply{}, errTimeout
+		}
+	}
+}
+
+//close stdinを閉じてプロセスの終了を待つ（終了しなければ強制的に終了させる. 2回目以降は何もしない）
+func (p *process) close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.requests)
+	p.mu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		//stdoutを読み切らないとWaitが終わらないことがある
+		for range p.replies {
+		}
+		done <- p.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+//httpClient localhostのHTTPサーバーにJSONをPOSTする
+type httpClient struct {
+	url    string
+	client *http.Client
+	closed bool
+}
+
+//newHTTPClient エンドポイントのURLを受け取り, httpClientを返す
+func newHTTPClient(url string) *httpClient {
+	return &httpClient{url: url, client: &http.Client{}}
+}
+
+//roundTrip 要求をPOSTし, 応答を返す
+func (h *httpClient) roundTrip(req Request, timeout time.Duration) (Reply, error) {
+	if h.closed {
+		return Reply{}, fmt.Errorf("controller is closed")
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return Reply{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(b))
+	if err != nil {
+		return Reply{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	res, err := h.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return Reply{}, errTimeout
+		}
+		return Reply{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Reply{}, fmt.Errorf("status %v", res.Status)
+	}
+	var reply Reply
+	if err := json.NewDecoder(res.Body).Decode(&reply); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return Reply{}, errTimeout
+		}
+		return Reply{}, fmt.Errorf("can't decode reply (%s)", err)
+	}
+	return reply, nil
+}
+
+//close 以降の要求を失敗させる（HTTPサーバーはシミュレータの外で管理するので終了させない）
+func (h *httpClient) close() error {
+	h.closed = true
+	return nil
+}
@@ -0,0 +1,53 @@
+import json
+import random
+import sys
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+# 行動の番号（action パッケージと同じ）
+UP, DOWN, LEFT, RIGHT, PICKUP, CLEAR, STAY = range(7)
+
+
+def decide(obs, agent):
+    """観測の特徴に従って, アイテムを拾ってデポに運ぶ単純な方策"""
+    valid = obs["valid_actions"][agent]
+    feature = obs["features"][agent]
+    if CLEAR in valid:
+        return CLEAR
+    if PICKUP in valid:
+        return PICKUP
+    if feature["load"] > 0 and feature["depot_dir"] != STAY:
+        return feature["depot_dir"]
+    if feature["item_dir"] != STAY:
+        return feature["item_dir"]
+    return random.choice(valid)
+
+
+def reply(req):
+    return {"turn": req["turn"], "actions": [decide(req["observation"], a) for a in req["agents"]]}
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        req = json.loads(self.rfile.read(int(self.headers["Content-Length"])))
+        body = json.dumps(reply(req)).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+def main():
+    # 引数なしなら標準入出力で, --http PORT ならlocalhostのHTTPサーバーとして動く
+    if len(sys.argv) > 2 and sys.argv[1] == "--http":
+        HTTPServer(("127.0.0.1", int(sys.argv[2])), Handler).serve_forever()
+        return
+    for line in sys.stdin:
+        print(json.dumps(reply(json.loads(line))), flush=True)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,159 @@
+package sim
+
+import (
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/heatmap"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//Congestion 衝突と渋滞の集計（Cellsで終わるものはマスごとの集計で, [y][x]の順に添字をとる）
+type Congestion struct {
+	BlockedMoves    []int   `json:"blocked_moves"`    //移動しようとして移動できなかった回数
+	VertexConflicts []int   `json:"vertex_conflicts"` //他のエージェントと同じマスに移動しようとした回数
+	SwapConflicts   []int   `json:"swap_conflicts"`   //他のエージェントとすれ違うように移動しようとした回数
+	WaitStreaks     []int   `json:"wait_streaks"`     //連続して移動できなかった期間（2ターン以上）の数
+	MaxWaitStreak   []int   `json:"max_wait_streak"`  //連続して移動できなかったターン数の最大値
+	Deadlocks       []int   `json:"deadlocks"`        //デッドロックに巻き込まれた回数
+	NumDeadlocks    int     `json:"num_deadlocks"`    //デッドロックの発生回数
+	BlockedCells    [][]int `json:"blocked_cells"`    //移動しようとして移動できなかった移動先のマス
+	VertexCells     [][]int `json:"vertex_cells"`     //複数のエージェントが移動しようとしたマス
+	SwapCells       [][]int `json:"swap_cells"`       //すれ違おうとしたエージェントがいたマス
+	DeadlockCells   [][]int `json:"deadlock_cells"`   //デッドロックに巻き込まれたエージェントがいたマス
+}
+
+//newCongestion エージェントの数とマップの大きさを受け取り, 空の集計を返す
+func newCongestion(numAgents int, H int, W int) Congestion {
+	return Congestion{
+		BlockedMoves:    make([]int, numAgents),
+		VertexConflicts: make([]int, numAgents),
+		SwapConflicts:   make([]int, numAgents),
+		WaitStreaks:     make([]int, numAgents),
+		MaxWaitStreak:   make([]int, numAgents),
+		Deadlocks:       make([]int, numAgents),
+		BlockedCells:    heatmap.NewGrid(H, W),
+		VertexCells:     heatmap.NewGrid(H, W),
+		SwapCells:       heatmap.NewGrid(H, W),
+		DeadlockCells:   heatmap.NewGrid(H, W),
+	}
+}
+
+//clone 全ての集計を複製したCongestionを返す
+func (c Congestion) clone() Congestion {
+	ret := c
+	ret.BlockedMoves = append([]int{}, c.BlockedMoves...)
+	ret.VertexConflicts = append([]int{}, c.VertexConflicts...)
+	ret.SwapConflicts = append([]int{}, c.SwapConflicts...)
+	ret.WaitStreaks = append([]int{}, c.WaitStreaks...)
+	ret.MaxWaitStreak = append([]int{}, c.MaxWaitStreak...)
+	ret.Deadlocks = append([]int{}, c.Deadlocks...)
+	ret.BlockedCells = heatmap.CloneGrid(c.BlockedCells)
+	ret.VertexCells = heatmap.CloneGrid(c.VertexCells)
+	ret.SwapCells = heatmap.CloneGrid(c.SwapCells)
+	ret.DeadlockCells = heatmap.CloneGrid(c.DeadlockCells)
+	return ret
+}
+
+//trackCongestion 1ステップの遷移を受け取り, 衝突と渋滞の集計を更新する
+func (sim *Simulator) trackCongestion(now *state.State, nxt *state.State, actions []int) {
+	c := &sim.Congestion
+	n := sim.Env.NumAgents
+	//移動しようとしたマス（移動しないならnil）
+	target := make([]*pos.Pos, n)
+	wanted := make(map[pos.Pos][]int)
+	currentID := make(map[pos.Pos]int)
+	for id, p := range now.AgentPos {
+		currentID[p] = id
+		switch actions[id] {
+		case action.UP, action.DOWN, action.LEFT, action.RIGHT:
+			nxtPos := pos.NextPos(p, actions[id], sim.Env.MapData)
+			//移動できないターンや進入できないマスへの移動は, 他のエージェントに阻まれたわけではないので数えない
+			if nxtPos != p && sim.Env.CanMove(id, now.Turn) && sim.Env.CanEnter(id, nxtPos) {
+				target[id] = &nxtPos
+				wanted[nxtPos] = append(wanted[nxtPos], id)
+			}
+		}
+	}
+	for p, ids := range wanted {
+		if len(ids) < 2 {
+			continue
+		}
+		c.VertexCells[p.Y][p.X]++
+		for _, id := range ids {
+			c.VertexConflicts[id]++
+		}
+	}
+	blocked := make([]bool, n)
+	for id := 0; id < n; id++ {
+		if target[id] == nil {
+			sim.waitStreak[id] = 0
+			continue
+		}
+		if other, exist := currentID[*target[id]]; exist && target[other] != nil && *target[other] == now.AgentPos[id] {
+			c.SwapConflicts[id]++
+			c.SwapCells[now.AgentPos[id].Y][now.AgentPos[id].X]++
+		}
+		if nxt.AgentPos[id] != now.AgentPos[id] {
+			sim.waitStreak[id] = 0
+			continue
+		}
+		blocked[id] = true
+		c.BlockedMoves[id]++
+		c.BlockedCells[target[id].Y][target[id].X]++
+		sim.waitStreak[id]++
+		if sim.waitStreak[id] == 2 {
+			c.WaitStreaks[id]++
+		}
+		if sim.waitStreak[id] > c.MaxWaitStreak[id] {
+			c.MaxWaitStreak[id] = sim.waitStreak[id]
+		}
+	}
+	//デッドロックの検出: 移動できなかったエージェントから, 移動先にいるエージェントへの待ちの関係が閉路をなし,
+	//閉路上の全員がDeadlockTurns以上連続して移動できていなければデッドロックとみなす
+	for id := 0; id < n; id++ {
+		if !blocked[id] {
+			sim.inDeadlock[id] = false
+		}
+	}
+	waitFor := func(id int) int {
+		if !blocked[id] {
+			return -1
+		}
+		other, exist := currentID[*target[id]]
+		if !exist || !blocked[other] {
+			return -1
+		}
+		return other
+	}
+	for start := 0; start < n; start++ {
+		if !blocked[start] || sim.inDeadlock[start] {
+			continue
+		}
+		//startから待ちの関係をたどり, startに戻ってくるかを調べる
+		cycle := []int{start}
+		cur := waitFor(start)
+		for cur != -1 && cur != start && len(cycle) <= n {
+			cycle = append(cycle, cur)
+			cur = waitFor(cur)
+		}
+		if cur != start {
+			continue
+		}
+		stuck := true
+		for _, id := range cycle {
+			if sim.waitStreak[id] < sim.Env.DeadlockTurns || sim.inDeadlock[id] {
+				stuck = false
+			}
+		}
+		if !stuck {
+			continue
+		}
+		c.NumDeadlocks++
+		for _, id := range cycle {
+			sim.inDeadlock[id] = true
+			c.Deadlocks[id]++
+			p := nxt.AgentPos[id]
+			c.DeadlockCells[p.Y][p.X]++
+		}
+	}
+}
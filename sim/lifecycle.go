@@ -0,0 +1,209 @@
+package sim
+
+import (
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//ItemRecord あるアイテムが出現してから運ばれるまでの記録（まだ起きていない出来事のターンは0）
+type ItemRecord struct {
+	ID         int     `json:"id"`
+	Type       int     `json:"type"`
+	Pos        pos.Pos `json:"pos"`
+	SpawnTurn  int     `json:"spawn_turn"`
+	PickupTurn int     `json:"pickup_turn"`
+	ClearTurn  int     `json:"clear_turn"`
+	ExpireTurn int     `json:"expire_turn"`
+	Agent      int     `json:"agent"` //拾ったエージェント（拾われていなければ-1）
+	Late       bool    `json:"late"`  //期限を過ぎて運ばれた場合に真
+}
+
+//AgentStats あるエージェントが各ターンに何をしていたかの集計
+type AgentStats struct {
+	Moving   int `json:"moving"`   //移動できたターン数
+	Blocked  int `json:"blocked"`  //移動しようとして移動できなかったターン数
+	Working  int `json:"working"`  //アイテムを拾う/回収することができたターン数
+	Idle     int `json:"idle"`     //それ以外のターン数
+	Charging int `json:"charging"` //充電ステーションで充電していたターン数
+	Dead     int `json:"dead"`     //電池が切れて動けなかったターン数
+	Broken   int `json:"broken"`   //故障して動けなかったターン数
+	Carrying int `json:"carrying"` //アイテムを持っていたターン数
+
+	Slips         int `json:"slips"`          //移動しようとして別の向きに滑った回数
+	FailedPickups int `json:"failed_pickups"` //アイテムを拾おうとして失敗した回数
+	Breakdowns    int `json:"breakdowns"`     //故障した回数
+}
+
+//record 1ステップの遷移（選ばれた行動と実際に実行された行動）を受け取り, アイテムの記録とエージェントの集計を更新する
+func (sim *Simulator) record(now *state.State, nxt *state.State, chosen []int, actions []int, appear []pos.Pos) {
+	//出現したアイテム（appearの順に番号が振られている）
+	for k, p := range appear {
+		sim.Heatmap.Spawns[p.Y][p.X]++
+		id := now.NumSpawned + k
+		for _, it := range nxt.PosItems[p] {
+			if it.ID == id {
+				sim.Items = append(sim.Items, ItemRecord{ID: id, Type: it.Type, Pos: p, SpawnTurn: it.SpawnTurn, Agent: -1})
+				break
+			}
+		}
+	}
+	for _, it := range nxt.Expired {
+		sim.Items[it.ID].ExpireTurn = nxt.Turn
+	}
+	for _, p := range nxt.AgentPos {
+		sim.Heatmap.Visits[p.Y][p.X]++
+	}
+	for i, act := range actions {
+		stats := &sim.AgentStats[i]
+		if len(now.AgentItems[i]) > 0 {
+			stats.Carrying++
+		}
+		if nxt.AgentRepair != nil && nxt.AgentRepair[i] > 0 && (now.AgentRepair == nil || now.AgentRepair[i] == 0) {
+			stats.Breakdowns++
+		}
+		if now.AgentRepair != nil && now.AgentRepair[i] > 0 {
+			stats.Broken++
+			continue
+		}
+		if now.AgentBattery != nil {
+			if act == action.STAY && sim.Env.IsCharger(now.AgentPos[i]) {
+				stats.Charging++
+				continue
+			}
+			if now.AgentBattery[i] == 0 {
+				stats.Dead++
+				continue
+			}
+		}
+		//選ばれた行動と実際に実行された行動が異なれば滑ったか拾うのに失敗した
+		if act != chosen[i] {
+			switch chosen[i] {
+			case action.UP, action.DOWN, action.LEFT, action.RIGHT:
+				if act != action.STAY {
+					stats.Slips++
+				}
+			case action.PICKUP:
+				stats.FailedPickups++
+			}
+		}
+		//Successはその場にとどまれば真になり拾う/回収することの成否を表さないので, 持っているアイテムの数の変化で判定する
+		switch act {
+		case action.UP, action.DOWN, action.LEFT, action.RIGHT:
+			if nxt.AgentPos[i] != now.AgentPos[i] {
+				stats.Moving++
+				continue
+			}
+			//移動できないターンや進入できないマスへの移動は何もしなかったものとする
+			if target := pos.NextPos(now.AgentPos[i], act, sim.Env.MapData); sim.Env.CanMove(i, now.Turn) && sim.Env.CanEnter(i, target) {
+				stats.Blocked++
+				continue
+			}
+		case action.PICKUP:
+			if len(nxt.AgentItems[i]) > len(now.AgentItems[i]) {
+				//拾ったアイテムは末尾に加わる
+				it := nxt.AgentItems[i][len(nxt.AgentItems[i])-1]
+				sim.Items[it.ID].PickupTurn = nxt.Turn
+				sim.Items[it.ID].Agent = i
+				sim.Heatmap.Pickups[now.AgentPos[i].Y][now.AgentPos[i].X]++
+				stats.Working++
+				continue
+			}
+		case action.CLEAR:
+			if len(nxt.AgentItems[i]) < len(now.AgentItems[i]) {
+				for _, it := range now.AgentItems[i] {
+					if sim.Env.Accepts(now.AgentPos[i], it.Type) {
+						sim.Items[it.ID].ClearTurn = nxt.Turn
+						sim.Items[it.ID].Late = it.IsLate(nxt.Turn)
+					}
+				}
+				stats.Working++
+				continue
+			}
+		}
+		stats.Idle++
+	}
+}
+
+//Percentile 昇順にソートされたスライスのp分位点を返す（空なら0）
+func Percentile(sorted []int, p float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+//mean スライスの平均を返す
+func mean(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return float64(sum) / float64(len(values))
+}
+
+//summarize アイテムの記録から結果を集計する
+func (sim *Simulator) summarize(result *Result) {
+	pickupLatencies := []int{}
+	deliveryLatencies := []int{}
+	for _, rec := range sim.Items {
+		if rec.PickupTurn > 0 {
+			pickupLatencies = append(pickupLatencies, rec.PickupTurn-rec.SpawnTurn)
+		}
+		if rec.ClearTurn > 0 {
+			deliveryLatencies = append(deliveryLatencies, rec.ClearTurn-rec.SpawnTurn)
+			if rec.Late {
+				result.LateItems++
+			}
+		}
+		if rec.ExpireTurn > 0 {
+			result.ExpiredItems++
+		}
+	}
+	sort.Ints(pickupLatencies)
+	sort.Ints(deliveryLatencies)
+	result.DeliveredItems = len(deliveryLatencies)
+	result.MeanWait = mean(deliveryLatencies)
+	result.MaxWait = Percentile(deliveryLatencies, 1)
+	result.WaitP50 = Percentile(deliveryLatencies, 0.5)
+	result.WaitP90 = Percentile(deliveryLatencies, 0.9)
+	result.WaitP99 = Percentile(deliveryLatencies, 0.99)
+	result.DeliveryLatencies = deliveryLatencies
+	result.MeanPickupLatency = mean(pickupLatencies)
+	result.MaxPickupLatency = Percentile(pickupLatencies, 1)
+	if turns := sim.State.Turn - 1; turns > 0 {
+		result.Throughput = float64(result.DeliveredItems) * 100 / float64(turns)
+	}
+	for _, items := range sim.State.PosItems {
+		result.ItemsOnFloor += len(items)
+	}
+	for _, items := range sim.State.AgentItems {
+		result.ItemsCarried += len(items)
+	}
+	if sim.floorItems > 0 {
+		result.KnownItemRatio = float64(sim.knownItems) / float64(sim.floorItems)
+	}
+	result.AgentStats = sim.AgentStats
+	result.Congestion = sim.Congestion
+	result.Heatmap = sim.Heatmap
+	if sim.Channel != nil {
+		result.Comm = sim.Channel.Stats
+	}
+	if sim.Auction != nil {
+		result.Auctions = sim.Auction.Auctions
+		result.Reassignments = sim.Auction.Changes
+	}
+	if sim.Planner != nil {
+		result.Planning = sim.Planner.Stats
+	}
+	if sim.Remote != nil {
+		result.Remote = sim.Remote.Stats
+	}
+	result.Items = sim.Items
+}
@@ -1,8 +1,39 @@
 package sim
 
+import (
+	"github.com/Div9851/warehouse-sim/comm"
+	"github.com/Div9851/warehouse-sim/heatmap"
+	"github.com/Div9851/warehouse-sim/mapf"
+	"github.com/Div9851/warehouse-sim/remote"
+)
+
 //Result シミュレーションの結果を表す構造体
 type Result struct {
-	TotalItems   int   `json:"total_items"`
-	PickupCounts []int `json:"pickup_counts"`
-	ClearCounts  []int `json:"clear_counts"`
+	TotalItems        int              `json:"total_items"`
+	PickupCounts      []int            `json:"pickup_counts"`
+	ClearCounts       []int            `json:"clear_counts"`
+	DeliveredItems    int              `json:"delivered_items"`     //デポに運ばれたアイテムの数
+	LateItems         int              `json:"late_items"`          //期限を過ぎてデポに運ばれたアイテムの数
+	ExpiredItems      int              `json:"expired_items"`       //拾われないまま消滅したアイテムの数
+	ItemsOnFloor      int              `json:"items_on_floor"`      //終了時に拾われずに残っているアイテムの数
+	ItemsCarried      int              `json:"items_carried"`       //終了時にエージェントが持っているアイテムの数
+	Throughput        float64          `json:"throughput"`          //100ターンあたりにデポに運ばれたアイテムの数
+	MeanWait          float64          `json:"mean_wait"`           //出現してからデポに運ばれるまでのターン数の平均
+	MaxWait           int              `json:"max_wait"`            //出現してからデポに運ばれるまでのターン数の最大値
+	WaitP50           int              `json:"wait_p50"`            //出現してからデポに運ばれるまでのターン数の中央値
+	WaitP90           int              `json:"wait_p90"`            //出現してからデポに運ばれるまでのターン数の90パーセンタイル
+	WaitP99           int              `json:"wait_p99"`            //出現してからデポに運ばれるまでのターン数の99パーセンタイル
+	DeliveryLatencies []int            `json:"delivery_latencies"`  //出現してからデポに運ばれるまでのターン数（昇順）
+	MeanPickupLatency float64          `json:"mean_pickup_latency"` //出現してから拾われるまでのターン数の平均
+	MaxPickupLatency  int              `json:"max_pickup_latency"`  //出現してから拾われるまでのターン数の最大値
+	KnownItemRatio    float64          `json:"known_item_ratio"`    //床のアイテムのうち, 各エージェントが置かれている座標を正しく知っていた割合
+	AgentStats        []AgentStats     `json:"agent_stats"`
+	Congestion        Congestion       `json:"congestion"`
+	Heatmap           *heatmap.Heatmap `json:"heatmap"`
+	Comm              comm.Stats       `json:"comm"`
+	Auctions          int              `json:"auctions"`      //競売を行った回数
+	Reassignments     int              `json:"reassignments"` //競売のやり直しで落札者が変わったアイテムの数
+	Planning          mapf.Stats       `json:"planning"`      //CBS, ECBS, PBS, PPでの経路計画の統計
+	Remote            remote.Stats     `json:"remote"`        //REMOTEのエージェントの外部のコントローラとのやり取りの集計
+	Items             []ItemRecord     `json:"items"`
 }
@@ -9,10 +9,18 @@ import (
 	"time"
 
 	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/auction"
+	"github.com/Div9851/warehouse-sim/comm"
 	"github.com/Div9851/warehouse-sim/env"
 	"github.com/Div9851/warehouse-sim/greedy"
+	"github.com/Div9851/warehouse-sim/heatmap"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/learn"
+	"github.com/Div9851/warehouse-sim/mapf"
 	"github.com/Div9851/warehouse-sim/mcts"
+	"github.com/Div9851/warehouse-sim/observe"
 	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/remote"
 	"github.com/Div9851/warehouse-sim/state"
 )
 
@@ -23,22 +31,42 @@ type Simulator struct {
 	LastActions  []int
 	TotalRewards []float64
 	LastRewards  []float64
-	LastAppear   *pos.Pos
+	LastAppear   []pos.Pos
 	Opt          []float64
 	TotalItems   int
 	PickupCounts []int
 	ClearCounts  []int
+	Items        []ItemRecord //出現したアイテムの記録（添字はアイテムの番号）
+	AgentStats   []AgentStats
+	Congestion   Congestion
+	Heatmap      *heatmap.Heatmap
+	waitStreak   []int  //各エージェントが連続して移動できなかったターン数
+	inDeadlock   []bool //各エージェントがデッドロックとして数えられている最中かどうか
 	SimRand      *rand.Rand
 	Rands        []*rand.Rand
 	Seed         int64
+
+	Beliefs    []*observe.Belief //各エージェントの観測に基づく推定（観測が制限されていなければnil）
+	knownItems int               //各ターンに各エージェントが正しく知っていた床のアイテムの数の合計
+	floorItems int               //各ターンの床のアイテムの数の合計（×エージェントの数）
+	Channel    *comm.Channel     //エージェント間の通信路（GREEDY_COMMのエージェントがいなければnil）
+	Auction    *auction.Auction  //オークションによるアイテムの割り当て（AUCTIONのエージェントがいなければnil）
+	Planner    *mapf.Planner     //全てのエージェントの経路をまとめて計画するプランナー（CBS, ECBS, PBS, PPのエージェントがいなければnil）
+	Policy     *learn.Policy     //学習済みの方策（LEARNEDのエージェントがいなければnil）
+
+	Remote *remote.Controller //外部のコントローラ（REMOTEのエージェントがいなければnil）
+
+	HumanActions []int //HUMANのエージェントが次のターンに行う行動（Nextを呼ぶ前に設定する. nilならその場にとどまる）
+	KeepHistory  int   //Undoで戻せるターン数の上限（0なら履歴を保存しない）
+	history      []snapshot
 }
 
-//New 環境設定とシード値を受け取り, シミュレータを返す
-func New(env *env.Env, seed int64) *Simulator {
+//New 環境設定とシード値を受け取り, シミュレータを返す（学習済みの方策の読み込みや外部のコントローラの起動に失敗したらエラーを返す）
+func New(env *env.Env, seed int64) (*Simulator, error) {
 	totalRewards := make([]float64, env.NumAgents)
-	agentItems := make([]int, env.NumAgents)
+	agentItems := make([][]item.Item, env.NumAgents)
 	agentPos := make([]pos.Pos, env.NumAgents)
-	posItems := make(map[pos.Pos]int)
+	posItems := make(map[pos.Pos][]item.Item)
 	pickupCounts := make([]int, env.NumAgents)
 	clearCounts := make([]int, env.NumAgents)
 	simRand := rand.New(rand.NewSource(seed))
@@ -47,18 +75,105 @@ func New(env *env.Env, seed int64) *Simulator {
 	for i := 0; i < env.NumAgents; i++ {
 		opt[i] = 0.5
 	}
+	//マップデータにSがあればその中から重複しないように, 進入できる初期位置を選ぶ（割り当てられることはenv.Loadで確かめている）
+	var starts []pos.Pos
+	if len(env.StartPos) > 0 {
+		starts, _ = env.MatchStarts(simRand.Perm(len(env.StartPos)))
+	}
 	for i := range rands {
 		rands[i] = rand.New(rand.NewSource(simRand.Int63()))
-		agentPos[i] = env.AllPos[simRand.Intn(len(env.AllPos))]
+		if starts != nil {
+			agentPos[i] = starts[i]
+			continue
+		}
+		for {
+			agentPos[i] = env.AllPos[simRand.Intn(len(env.AllPos))]
+			//停止禁止のマスと, そのエージェントが進入できないマスは初期位置にしない（選べるマスがあることはenv.Loadで確かめている）
+			if !env.IsNoStop(agentPos[i]) && env.CanEnter(i, agentPos[i]) {
+				break
+			}
+		}
 	}
 	randomValues := make(map[pos.Pos]float64)
-	randomValues[env.DepotPos] = simRand.Float64()
+	for _, depot := range env.Depots {
+		randomValues[depot.Pos] = simRand.Float64()
+	}
 	for _, pos := range env.AllPos {
 		randomValues[pos] = simRand.Float64()
 	}
 	success := make([]bool, env.NumAgents)
 	state := state.New(1, agentItems, agentPos, posItems, randomValues, success)
-	return &Simulator{Env: env, State: state, TotalRewards: totalRewards, Opt: opt, PickupCounts: pickupCounts, ClearCounts: clearCounts, SimRand: simRand, Rands: rands, Seed: seed}
+	if env.UsesBattery() {
+		state.AgentBattery = make([]int, env.NumAgents)
+		for i := range state.AgentBattery {
+			state.AgentBattery[i] = env.Battery.Capacity
+		}
+	}
+	var channel *comm.Channel
+	if env.UsesComm() {
+		channel = comm.New(env, rand.New(rand.NewSource(simRand.Int63())))
+	}
+	var auc *auction.Auction
+	if env.UsesAlgorithm("AUCTION") {
+		auc = auction.New(env)
+	}
+	var planner *mapf.Planner
+	if env.UsesMAPF() {
+		planner = mapf.NewPlanner(env)
+	}
+	var policy *learn.Policy
+	if env.UsesAlgorithm("LEARNED") {
+		var err error
+		policy, err = learn.Load(env.PolicyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var controller *remote.Controller
+	if env.UsesAlgorithm("REMOTE") {
+		var err error
+		controller, err = remote.New(env)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var beliefs []*observe.Belief
+	if env.IsPartiallyObservable() {
+		beliefs = make([]*observe.Belief, env.NumAgents)
+		for i := range beliefs {
+			beliefs[i] = observe.New(i, state, env)
+		}
+	}
+	agentStats := make([]AgentStats, env.NumAgents)
+	congestion := newCongestion(env.NumAgents, env.MapDataH, env.MapDataW)
+	hm := heatmap.New(env.MapDataH, env.MapDataW)
+	hm.Runs = 1
+	hm.Blocked = congestion.BlockedCells
+	for _, p := range agentPos {
+		hm.Visits[p.Y][p.X]++
+	}
+	return &Simulator{
+		Env:          env,
+		State:        state,
+		TotalRewards: totalRewards,
+		Opt:          opt,
+		PickupCounts: pickupCounts,
+		ClearCounts:  clearCounts,
+		AgentStats:   agentStats,
+		Congestion:   congestion,
+		Heatmap:      hm,
+		Beliefs:      beliefs,
+		Channel:      channel,
+		Auction:      auc,
+		Planner:      planner,
+		Policy:       policy,
+		Remote:       controller,
+		waitStreak:   make([]int, env.NumAgents),
+		inDeadlock:   make([]bool, env.NumAgents),
+		SimRand:      simRand,
+		Rands:        rands,
+		Seed:         seed,
+	}, nil
 }
 
 //Do シミュレーションを実行し, 実行時間を返す
@@ -72,6 +187,8 @@ func (sim *Simulator) Do(verbose bool) float64 {
 			break
 		}
 	}
+	//最後まで進めたので外部のコントローラを終了させる（終了に失敗しても結果には影響しないので無視する）
+	sim.Close()
 	endTime := time.Now()
 	processTime := endTime.Sub(startTime).Seconds()
 	return processTime
@@ -79,17 +196,117 @@ func (sim *Simulator) Do(verbose bool) float64 {
 
 //Next シミュレーションを1ステップ進める（すでに終了していればfalseを返す）
 func (sim *Simulator) Next() bool {
+	return sim.Step(nil)
+}
+
+//Step 与えられた行動でシミュレーションを1ステップ進める（すでに終了していればfalseを返す）
+//（actionsがnilなら全てのエージェントが, 行動が-1のエージェントは設定されたアルゴリズムで行動を決める）
+func (sim *Simulator) Step(actions []int) bool {
 	if sim.State.Turn == sim.Env.LastTurn {
 		return false
 	}
+	if sim.KeepHistory > 0 {
+		sim.save()
+	}
+	auto := actions == nil
+	for _, act := range actions {
+		auto = auto || act == -1
+	}
+	if auto {
+		decided := sim.decide()
+		if actions == nil {
+			actions = decided
+		} else {
+			actions = append([]int{}, actions...)
+			for id, act := range actions {
+				if act == -1 {
+					actions[id] = decided[id]
+				}
+			}
+		}
+	}
+	nxtState, lastActions, lastAppear, lastRewards := state.ReplayState(sim.State, actions, sim.Env, sim.SimRand)
+	//Successはその場にとどまれば真になるので, recordと同じく持っているアイテムの数の変化で数える
+	for i, act := range lastActions {
+		if act == action.CLEAR && len(nxtState.AgentItems[i]) < len(sim.State.AgentItems[i]) {
+			sim.ClearCounts[i]++
+		}
+		if act == action.PICKUP && len(nxtState.AgentItems[i]) > len(sim.State.AgentItems[i]) {
+			sim.PickupCounts[i]++
+		}
+	}
+	sim.TotalItems += len(lastAppear)
+	sim.record(sim.State, nxtState, actions, lastActions, lastAppear)
+	sim.trackCongestion(sim.State, nxtState, lastActions)
+	sim.State = nxtState
+	if sim.Beliefs != nil {
+		for _, b := range sim.Beliefs {
+			b.Update(sim.State, sim.Env)
+			sim.knownItems += b.NumKnownItems(sim.State)
+		}
+		for _, items := range sim.State.PosItems {
+			sim.floorItems += len(items) * sim.Env.NumAgents
+		}
+	}
+	sim.LastActions = lastActions
+	sim.LastRewards = lastRewards
+	sim.LastAppear = lastAppear
+	for i, r := range lastRewards {
+		sim.TotalRewards[i] += r
+	}
+	return true
+}
+
+//Close 外部のコントローラを終了させる（何度呼んでもよい. Undoで戻すことがあるので, 最後のターンに達しても自動では終了させない）
+func (sim *Simulator) Close() error {
+	if sim.Remote == nil {
+		return nil
+	}
+	return sim.Remote.Close()
+}
+
+//decide 設定されたアルゴリズムで各エージェントの行動を決める
+func (sim *Simulator) decide() []int {
 	actions := make([]int, sim.Env.NumAgents)
+	//各エージェントが送るメッセージ
+	outgoing := make([]*comm.Message, sim.Env.NumAgents)
+	//このターンまでに届くメッセージを受信箱に入れる
+	if sim.Channel != nil {
+		sim.Channel.Deliver(sim.State.Turn)
+	}
+	//新しいアイテムが出現していれば競売をやり直す
+	if sim.Auction != nil {
+		sim.Auction.Update(sim.State, sim.LastAppear)
+	}
+	//新しいアイテムが出現したり割り当てが変わったりしていれば経路を計画し直す
+	if sim.Planner != nil {
+		goals, ok := greedy.Goals(sim.State, sim.Env)
+		sim.Planner.Update(sim.State, goals, ok, sim.LastAppear)
+	}
+	//REMOTEのエージェントの行動はまとめて外部のコントローラに問い合わせる
+	var remoteActions []int
+	var remoteOK []bool
+	if sim.Remote != nil {
+		agents := []int{}
+		for id, algo := range sim.Env.Algorithms {
+			if algo == "REMOTE" {
+				agents = append(agents, id)
+			}
+		}
+		remoteActions, remoteOK = sim.Remote.Actions(sim.State, agents)
+	}
 	wg := &sync.WaitGroup{}
 	for i := 0; i < sim.Env.NumAgents; i++ {
 		wg.Add(1)
 		go func(id int) {
+			//観測が制限されているなら, 各エージェントは自分の推定に基づいて行動を決める
+			view := sim.State
+			if sim.Beliefs != nil {
+				view = sim.Beliefs[id].View(sim.State)
+			}
 			switch sim.Env.Algorithms[id] {
 			case "MCTS":
-				actions[id] = mcts.MCTS(id, sim.State, sim.Env, sim.Rands[id], 0)
+				actions[id] = mcts.MCTS(id, view, sim.Env, sim.Rands[id], 0)
 			case "MCTS_OPT":
 				nxtOpt := sim.Opt[id]
 				if sim.State.Success[id] {
@@ -98,43 +315,60 @@ func (sim *Simulator) Next() bool {
 					nxtOpt = math.Max(nxtOpt-0.2, 0)
 				}
 				sim.Opt[id] = nxtOpt
-				actions[id] = mcts.MCTS(id, sim.State, sim.Env, sim.Rands[id], sim.Opt[id])
+				actions[id] = mcts.MCTS(id, view, sim.Env, sim.Rands[id], sim.Opt[id])
+			case "HUNGARIAN":
+				ret, _ := greedy.Hungarian(view, sim.Env, sim.Rands[id], sim.Env.GreedyCA)
+				actions[id] = ret[id]
+			case "AUCTION":
+				ret, _ := sim.Auction.Actions(view, sim.Rands[id], sim.Env.GreedyCA)
+				actions[id] = ret[id]
+			case "CBS", "ECBS", "PBS", "PP":
+				actions[id] = sim.Planner.Action(id, sim.State)
+			case "LEARNED":
+				actions[id] = sim.Policy.Act(id, view, sim.Env, sim.Rands[id])
+			case "REMOTE":
+				switch {
+				case remoteOK[id]:
+					actions[id] = remoteActions[id]
+				case sim.Env.Remote.Fallback == "STAY":
+					actions[id] = action.STAY
+				default:
+					ret, _ := greedy.Greedy(view, sim.Env, sim.Rands[id], sim.Env.GreedyCA)
+					actions[id] = ret[id]
+				}
+			case "HUMAN":
+				actions[id] = action.STAY
+				if sim.HumanActions != nil {
+					actions[id] = sim.HumanActions[id]
+				}
+			case "GREEDY_COMM":
+				act, msg := greedy.GreedyComm(id, view, sim.Env, sim.Rands[id], sim.Env.GreedyCA, sim.Channel.Inbox(id))
+				actions[id] = act
+				outgoing[id] = &msg
 			default:
-				ret, _ := greedy.Greedy(sim.State, sim.Env, sim.Rands[id], sim.Env.GreedyCA)
+				ret, _ := greedy.Greedy(view, sim.Env, sim.Rands[id], sim.Env.GreedyCA)
 				actions[id] = ret[id]
 			}
 			wg.Done()
 		}(i)
 	}
 	wg.Wait()
-	nxtState, lastActions, lastAppear, lastRewards := state.NextState(sim.State, actions, sim.Env, sim.SimRand)
-	for i, act := range lastActions {
-		if act == action.CLEAR && nxtState.Success[i] {
-			sim.ClearCounts[i]++
-		}
-		if act == action.PICKUP && nxtState.Success[i] {
-			sim.PickupCounts[i]++
+	for _, msg := range outgoing {
+		if msg != nil {
+			sim.Channel.Broadcast(*msg, sim.State.AgentPos)
 		}
 	}
-	if lastAppear != nil {
-		sim.TotalItems++
-	}
-	sim.State = nxtState
-	sim.LastActions = lastActions
-	sim.LastRewards = lastRewards
-	sim.LastAppear = lastAppear
-	for i, r := range lastRewards {
-		sim.TotalRewards[i] += r
-	}
-	return true
+	return actions
 }
 
 //DumpMap マップデータを返す
 func (sim *Simulator) DumpMap() []string {
 	mapData := make([]string, len(sim.Env.MapData))
 	copy(mapData, sim.Env.MapData)
-	pos := sim.Env.DepotPos
-	mapData[pos.Y] = mapData[pos.Y][:pos.X] + "D" + mapData[pos.Y][pos.X+1:]
+	for _, depot := range sim.Env.Depots {
+		pos := depot.Pos
+		mapData[pos.Y] = mapData[pos.Y][:pos.X] + "D" + mapData[pos.Y][pos.X+1:]
+	}
 	for pos := range sim.State.PosItems {
 		mapData[pos.Y] = mapData[pos.Y][:pos.X] + "*" + mapData[pos.Y][pos.X+1:]
 	}
@@ -152,9 +386,11 @@ func (sim *Simulator) DumpState() string {
 	for _, row := range mapData {
 		fmt.Fprintln(&b, row)
 	}
-	if sim.LastAppear != nil {
+	if len(sim.LastAppear) > 0 {
 		fmt.Fprintln(&b, "[NEW ITEM]")
-		fmt.Fprintf(&b, "(%v, %v)\n", sim.LastAppear.X, sim.LastAppear.Y)
+		for _, p := range sim.LastAppear {
+			fmt.Fprintf(&b, "(%v, %v)\n", p.X, p.Y)
+		}
 	}
 	if sim.LastActions != nil {
 		fmt.Fprintln(&b, "[ACTIONS]")
@@ -182,9 +418,23 @@ func (sim *Simulator) DumpState() string {
 	}
 	fmt.Fprintln(&b, "[ITEMS]")
 	for i, items := range sim.State.AgentItems {
-		fmt.Fprintf(&b, "agent %v: %v ", i, items)
+		fmt.Fprintf(&b, "agent %v: %v ", i, len(items))
 	}
 	fmt.Fprintln(&b)
+	if sim.State.AgentBattery != nil {
+		fmt.Fprintln(&b, "[BATTERY]")
+		for i, battery := range sim.State.AgentBattery {
+			fmt.Fprintf(&b, "agent %v: %v ", i, battery)
+		}
+		fmt.Fprintln(&b)
+	}
+	if sim.State.AgentRepair != nil {
+		fmt.Fprintln(&b, "[REPAIR]")
+		for i, repair := range sim.State.AgentRepair {
+			fmt.Fprintf(&b, "agent %v: %v ", i, repair)
+		}
+		fmt.Fprintln(&b)
+	}
 	fmt.Fprintln(&b, "[REWARDS]")
 	for i, r := range sim.TotalRewards {
 		var lastReward float64
@@ -206,5 +456,7 @@ func (sim *Simulator) DumpState() string {
 
 //GetResult シミュレーションの結果を返す
 func (sim *Simulator) GetResult() *Result {
-	return &Result{TotalItems: sim.TotalItems, PickupCounts: sim.PickupCounts, ClearCounts: sim.ClearCounts}
+	result := &Result{TotalItems: sim.TotalItems, PickupCounts: sim.PickupCounts, ClearCounts: sim.ClearCounts}
+	sim.summarize(result)
+	return result
 }
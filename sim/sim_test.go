@@ -0,0 +1,292 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+func load(t *testing.T) *env.Env {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Algorithms = []string{"GREEDY", "GREEDY", "GREEDY"}
+	return e
+}
+
+func TestFailedPickup(t *testing.T) {
+	s, err := New(load(t), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//エージェント1はすでにアイテムを持っている
+	s.State.AgentItems[1] = []item.Item{item.New(0, 0, 1, 0)}
+	s.State.NumSpawned = 1
+	s.Items = []ItemRecord{{ID: 0, SpawnTurn: 1, PickupTurn: 1, Agent: 1}}
+	//アイテムのないマスで拾おうとする
+	s.Step([]int{action.PICKUP, action.PICKUP, action.STAY})
+	if !s.State.Success[0] || !s.State.Success[1] {
+		t.Fatal("agents staying in place should succeed")
+	}
+	if s.Items[0].PickupTurn != 1 || s.Items[0].Agent != 1 {
+		t.Fatalf("failed pickup should not overwrite the record, but `%+v`", s.Items[0])
+	}
+	if s.AgentStats[0].Working != 0 || s.AgentStats[0].Idle != 1 || s.AgentStats[1].Working != 0 {
+		t.Fatalf("failed pickups should count as idle, but `%+v`", s.AgentStats)
+	}
+	if s.PickupCounts[0] != 0 || s.PickupCounts[1] != 0 {
+		t.Fatalf("failed pickups should not be counted, but `%v`", s.PickupCounts)
+	}
+}
+
+//capture 巻き戻しの前後で比べるために, シミュレータの集計と内部状態を文字列にする
+func capture(t *testing.T, s *Simulator) string {
+	result, err := json.Marshal(s.GetResult())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	b.Write(result)
+	fmt.Fprint(&b, s.State.Turn, s.waitStreak, s.inDeadlock, s.knownItems, s.floorItems)
+	for _, belief := range s.Beliefs {
+		fmt.Fprint(&b, *belief)
+	}
+	if s.Channel != nil {
+		for id := range s.State.AgentPos {
+			fmt.Fprint(&b, s.Channel.Inbox(id))
+		}
+	}
+	if s.Auction != nil {
+		fmt.Fprint(&b, s.Auction.Bundles)
+	}
+	if s.Planner != nil {
+		fmt.Fprint(&b, s.Planner.Paths, s.Planner.Goals)
+	}
+	return b.String()
+}
+
+func TestUndo(t *testing.T) {
+	cases := []struct {
+		name       string
+		algorithms []string
+		radius     int
+	}{
+		{"comm, auction and mapf", []string{"GREEDY_COMM", "AUCTION", "PP"}, 0},
+		{"sensing", []string{"GREEDY", "GREEDY_COMM", "GREEDY"}, 2},
+	}
+	for _, c := range cases {
+		e := load(t)
+		e.Algorithms = c.algorithms
+		e.Sensing.Radius = c.radius
+		s, err := New(e, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.KeepHistory = 3
+		for i := 0; i < 20; i++ {
+			s.Next()
+		}
+		before := capture(t, s)
+		s.Next()
+		s.Next()
+		if !s.Undo() || !s.Undo() {
+			t.Fatalf("%v: undo should succeed", c.name)
+		}
+		if after := capture(t, s); after != before {
+			t.Fatalf("%v: undo should restore\n%v\nbut\n%v", c.name, before, after)
+		}
+		//戻してから進めても二重に数えない
+		s.Next()
+		visits := 0
+		for _, row := range s.Heatmap.Visits {
+			for _, v := range row {
+				visits += v
+			}
+		}
+		busy := 0
+		for _, stats := range s.AgentStats {
+			busy += stats.Moving + stats.Blocked + stats.Working + stats.Idle + stats.Charging + stats.Dead + stats.Broken
+		}
+		if visits != s.State.Turn*e.NumAgents || busy != (s.State.Turn-1)*e.NumAgents {
+			t.Fatalf("%v: counters should match turn %v, but visits %v, agent turns %v", c.name, s.State.Turn, visits, busy)
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	e := load(t)
+	e.AppearProb = 0
+	s, err := New(e, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.State.AgentPos = []pos.Pos{pos.New(2, 3), pos.New(6, 6), pos.New(6, 0)}
+	s.State.PosItems[pos.New(1, 3)] = []item.Item{item.New(0, 0, 1, 0)}
+	s.State.NumSpawned = 1
+	s.Items = []ItemRecord{{ID: 0, Pos: pos.New(1, 3), SpawnTurn: 1, Agent: -1}}
+	//エージェント0がアイテムを拾ってデポ(0, 3)に運び, 他のエージェントはとどまる
+	for _, act := range []int{action.LEFT, action.PICKUP, action.LEFT, action.CLEAR} {
+		s.Step([]int{act, action.STAY, action.STAY})
+	}
+	rec := s.Items[0]
+	if rec.PickupTurn != 3 || rec.ClearTurn != 5 || rec.Agent != 0 {
+		t.Fatalf("item should be picked up at turn 3 and cleared at turn 5 by agent 0, but `%+v`", rec)
+	}
+	result := s.GetResult()
+	if result.DeliveredItems != 1 || result.MeanPickupLatency != 2 || result.MeanWait != 4 || result.Throughput != 25 {
+		t.Fatalf("result should have 1 delivery with latencies 2 and 4 and throughput 25, but `%+v`", result)
+	}
+	want := []AgentStats{{Moving: 2, Working: 2, Carrying: 2}, {Idle: 4}, {Idle: 4}}
+	for id := range want {
+		if result.AgentStats[id] != want[id] {
+			t.Fatalf("stats of agent %v should be `%+v`, but `%+v`", id, want[id], result.AgentStats[id])
+		}
+	}
+	if result.PickupCounts[0] != 1 || result.ClearCounts[0] != 1 || result.Heatmap.Pickups[3][1] != 1 {
+		t.Fatalf("pickup at (1, 3) and clear should be counted once, but `%v` `%v`", result.PickupCounts, result.ClearCounts)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	cases := []struct {
+		name  string
+		items []ItemRecord
+		turn  int
+		want  Result
+	}{
+		{"no items", nil, 11, Result{}},
+		{
+			"deliveries",
+			[]ItemRecord{
+				{SpawnTurn: 1, PickupTurn: 2, ClearTurn: 3},
+				{SpawnTurn: 2, PickupTurn: 4, ClearTurn: 6},
+				{SpawnTurn: 1, PickupTurn: 7, ClearTurn: 10, Late: true},
+				{SpawnTurn: 3, PickupTurn: 5},
+				{SpawnTurn: 4, ExpireTurn: 9},
+			},
+			11,
+			Result{DeliveredItems: 3, LateItems: 1, ExpiredItems: 1, Throughput: 30, MeanWait: 5, MaxWait: 9, WaitP50: 4, WaitP90: 4, WaitP99: 4, MeanPickupLatency: 2.75, MaxPickupLatency: 6},
+		},
+	}
+	for _, c := range cases {
+		s, err := New(load(t), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Items = c.items
+		s.State.Turn = c.turn
+		var got Result
+		s.summarize(&got)
+		if got.DeliveredItems != c.want.DeliveredItems || got.LateItems != c.want.LateItems || got.ExpiredItems != c.want.ExpiredItems || got.Throughput != c.want.Throughput {
+			t.Fatalf("%v: counts should be `%+v`, but `%+v`", c.name, c.want, got)
+		}
+		if got.MeanWait != c.want.MeanWait || got.MaxWait != c.want.MaxWait || got.WaitP50 != c.want.WaitP50 || got.WaitP90 != c.want.WaitP90 || got.WaitP99 != c.want.WaitP99 {
+			t.Fatalf("%v: waits should be `%+v`, but `%+v`", c.name, c.want, got)
+		}
+		if got.MeanPickupLatency != c.want.MeanPickupLatency || got.MaxPickupLatency != c.want.MaxPickupLatency {
+			t.Fatalf("%v: pickup latencies should be `%+v`, but `%+v`", c.name, c.want, got)
+		}
+	}
+}
+
+func TestTrackCongestion(t *testing.T) {
+	cases := []struct {
+		name      string
+		now       []pos.Pos
+		actions   []int
+		nxt       []pos.Pos
+		turns     int
+		blocked   []int
+		vertex    []int
+		swap      []int
+		maxWait   []int
+		deadlock  []int
+		moveEvery []int //各エージェントが何ターンに1回移動できるか（nilなら毎ターン）
+	}{
+		{
+			//すれ違おうとして動けないまま5ターン経てばデッドロック
+			"swap deadlock",
+			[]pos.Pos{pos.New(2, 3), pos.New(3, 3), pos.New(6, 6)},
+			[]int{action.RIGHT, action.LEFT, action.STAY},
+			[]pos.Pos{pos.New(2, 3), pos.New(3, 3), pos.New(6, 6)},
+			6,
+			[]int{6, 6, 0}, []int{0, 0, 0}, []int{6, 6, 0}, []int{6, 6, 0}, []int{1, 1, 0}, nil,
+		},
+		{
+			//とどまっているエージェントに阻まれているだけならデッドロックではない
+			"blocked by staying agent",
+			[]pos.Pos{pos.New(2, 3), pos.New(3, 3), pos.New(6, 6)},
+			[]int{action.RIGHT, action.STAY, action.STAY},
+			[]pos.Pos{pos.New(2, 3), pos.New(3, 3), pos.New(6, 6)},
+			6,
+			[]int{6, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}, []int{6, 0, 0}, []int{0, 0, 0}, nil,
+		},
+		{
+			"vertex conflict",
+			[]pos.Pos{pos.New(2, 3), pos.New(4, 3), pos.New(6, 6)},
+			[]int{action.RIGHT, action.LEFT, action.STAY},
+			[]pos.Pos{pos.New(3, 3), pos.New(4, 3), pos.New(6, 6)},
+			1,
+			[]int{0, 1, 0}, []int{1, 1, 0}, []int{0, 0, 0}, []int{0, 1, 0}, []int{0, 0, 0}, nil,
+		},
+		{
+			//2ターンに1回しか移動できないエージェントが奇数ターンにとどまっても阻まれたことにはならない
+			"move_every",
+			[]pos.Pos{pos.New(0, 0), pos.New(4, 3), pos.New(6, 6)},
+			[]int{action.STAY, action.LEFT, action.STAY},
+			[]pos.Pos{pos.New(0, 0), pos.New(4, 3), pos.New(6, 6)},
+			6,
+			[]int{0, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}, []int{1, 2, 1},
+		},
+	}
+	for _, c := range cases {
+		e := load(t)
+		for _, every := range c.moveEvery {
+			e.AgentProfiles = append(e.AgentProfiles, env.Profile{MoveEvery: every})
+		}
+		s, err := New(e, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := &state.State{Turn: 1, AgentPos: c.now}
+		nxt := &state.State{AgentPos: c.nxt}
+		for turn := 0; turn < c.turns; turn++ {
+			s.trackCongestion(now, nxt, c.actions)
+		}
+		got := s.Congestion
+		if fmt.Sprint(got.BlockedMoves, got.VertexConflicts, got.SwapConflicts, got.MaxWaitStreak, got.Deadlocks) != fmt.Sprint(c.blocked, c.vertex, c.swap, c.maxWait, c.deadlock) {
+			t.Fatalf("%v: blocked, vertex, swap, max wait and deadlocks should be `%v %v %v %v %v`, but `%v %v %v %v %v`", c.name,
+				c.blocked, c.vertex, c.swap, c.maxWait, c.deadlock, got.BlockedMoves, got.VertexConflicts, got.SwapConflicts, got.MaxWaitStreak, got.Deadlocks)
+		}
+		if wantNum := c.deadlock[0]; got.NumDeadlocks != wantNum {
+			t.Fatalf("%v: number of deadlocks should be `%v`, but `%v`", c.name, wantNum, got.NumDeadlocks)
+		}
+	}
+}
+
+func TestForbiddenMove(t *testing.T) {
+	e := load(t)
+	e.AppearProb = 0
+	e.AgentProfiles = []env.Profile{{MoveEvery: 1}, {MoveEvery: 2}, {MoveEvery: 1}}
+	s, err := New(e, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.State.AgentPos = []pos.Pos{pos.New(0, 0), pos.New(4, 3), pos.New(6, 6)}
+	//ターン1にはエージェント1は移動できない
+	s.Step([]int{action.STAY, action.LEFT, action.STAY})
+	if stats := s.AgentStats[1]; stats.Blocked != 0 || stats.Idle != 1 {
+		t.Fatalf("move skipped by move_every should count as idle, but `%+v`", stats)
+	}
+	if s.Congestion.BlockedMoves[1] != 0 {
+		t.Fatalf("move skipped by move_every should not be a blocked move, but `%v`", s.Congestion.BlockedMoves)
+	}
+}
@@ -0,0 +1,117 @@
+package sim
+
+import (
+	"github.com/Div9851/warehouse-sim/auction"
+	"github.com/Div9851/warehouse-sim/comm"
+	"github.com/Div9851/warehouse-sim/heatmap"
+	"github.com/Div9851/warehouse-sim/mapf"
+	"github.com/Div9851/warehouse-sim/observe"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//snapshot 巻き戻すために保存する, あるターンのシミュレータの状態
+//（乱数と外部のコントローラは巻き戻さないので, 戻してから進めたターンは元と異なることがある）
+type snapshot struct {
+	state        *state.State
+	lastActions  []int
+	lastRewards  []float64
+	lastAppear   []pos.Pos
+	totalRewards []float64
+	totalItems   int
+	pickupCounts []int
+	clearCounts  []int
+	items        []ItemRecord
+	agentStats   []AgentStats
+	opt          []float64
+	congestion   Congestion
+	heatmap      *heatmap.Heatmap
+	waitStreak   []int
+	inDeadlock   []bool
+	beliefs      []*observe.Belief
+	knownItems   int
+	floorItems   int
+	channel      *comm.Channel
+	auction      *auction.Auction
+	planner      *mapf.Planner
+}
+
+//save 現在の状態を履歴に積む（履歴がKeepHistoryを超えたら古いものから捨てる）
+func (sim *Simulator) save() {
+	snap := snapshot{
+		state:        sim.State,
+		lastActions:  sim.LastActions,
+		lastRewards:  sim.LastRewards,
+		lastAppear:   sim.LastAppear,
+		totalRewards: append([]float64{}, sim.TotalRewards...),
+		totalItems:   sim.TotalItems,
+		pickupCounts: append([]int{}, sim.PickupCounts...),
+		clearCounts:  append([]int{}, sim.ClearCounts...),
+		items:        append([]ItemRecord{}, sim.Items...),
+		agentStats:   append([]AgentStats{}, sim.AgentStats...),
+		opt:          append([]float64{}, sim.Opt...),
+		congestion:   sim.Congestion.clone(),
+		heatmap:      sim.Heatmap.Clone(),
+		waitStreak:   append([]int{}, sim.waitStreak...),
+		inDeadlock:   append([]bool{}, sim.inDeadlock...),
+		knownItems:   sim.knownItems,
+		floorItems:   sim.floorItems,
+	}
+	//ヒートマップの移動に失敗した回数は衝突と渋滞の集計と共有する
+	snap.heatmap.Blocked = snap.congestion.BlockedCells
+	if sim.Beliefs != nil {
+		snap.beliefs = make([]*observe.Belief, len(sim.Beliefs))
+		for i, b := range sim.Beliefs {
+			snap.beliefs[i] = b.Clone()
+		}
+	}
+	if sim.Channel != nil {
+		snap.channel = sim.Channel.Clone()
+	}
+	if sim.Auction != nil {
+		snap.auction = sim.Auction.Clone()
+	}
+	if sim.Planner != nil {
+		snap.planner = sim.Planner.Clone()
+	}
+	sim.history = append(sim.history, snap)
+	if len(sim.history) > sim.KeepHistory {
+		sim.history = sim.history[len(sim.history)-sim.KeepHistory:]
+	}
+}
+
+//Undo 1ターン前の状態に戻す（戻せる履歴がなければfalseを返す）
+func (sim *Simulator) Undo() bool {
+	if len(sim.history) == 0 {
+		return false
+	}
+	snap := sim.history[len(sim.history)-1]
+	sim.history = sim.history[:len(sim.history)-1]
+	sim.State = snap.state
+	sim.LastActions = snap.lastActions
+	sim.LastRewards = snap.lastRewards
+	sim.LastAppear = snap.lastAppear
+	sim.TotalRewards = snap.totalRewards
+	sim.TotalItems = snap.totalItems
+	sim.PickupCounts = snap.pickupCounts
+	sim.ClearCounts = snap.clearCounts
+	sim.Items = snap.items
+	sim.AgentStats = snap.agentStats
+	sim.Opt = snap.opt
+	sim.Congestion = snap.congestion
+	sim.Heatmap = snap.heatmap
+	sim.waitStreak = snap.waitStreak
+	sim.inDeadlock = snap.inDeadlock
+	sim.Beliefs = snap.beliefs
+	sim.knownItems = snap.knownItems
+	sim.floorItems = snap.floorItems
+	sim.Channel = snap.channel
+	sim.Auction = snap.auction
+	sim.Planner = snap.planner
+	return true
+}
+
+//CanUndo 1ターン前の状態に戻せるかどうかを返す
+func (sim *Simulator) CanUndo() bool {
+	return len(sim.history) > 0
+}
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//到着過程の種類（関数の引数のenvがパッケージ名を隠すので, パッケージの定数に別名を付けておく）
+const (
+	arrivalPoisson = env.ArrivalPoisson
+	arrivalBatch   = env.ArrivalBatch
+)
+
+//nextArrivals 現在の状態, 環境設定, 乱数生成器, 注文の履歴を再生するかどうかを受け取り
+//次のターンにアイテムが出現する座標のスライス（同じ座標に複数出現する場合は重複する）と次のバーストの残りターン数を返す
+func nextArrivals(state *State, env *env.Env, rnd *rand.Rand, replay bool) ([]pos.Pos, int) {
+	if env.Trace != nil {
+		if replay {
+			return env.Trace[state.Turn+1], state.Burst
+		}
+		//計画では到着過程の設定に従う（出現し得るマスがなければ出現させない）
+		if len(env.SpawnPos) == 0 {
+			return []pos.Pos{}, state.Burst
+		}
+	}
+	arrival := &env.Arrival
+	burst := state.Burst
+	if burst > 0 {
+		burst--
+	} else if arrival.BurstProb > 0 && rnd.Float64() < arrival.BurstProb {
+		burst = arrival.BurstTurns
+	}
+	scale := demandScale(state.Turn, env)
+	if burst > 0 {
+		scale *= arrival.BurstFactor
+	}
+	appear := []pos.Pos{}
+	switch arrival.Model {
+	case arrivalPoisson:
+		n := samplePoisson(arrival.Rate*scale, rnd)
+		for i := 0; i < n; i++ {
+			appear = append(appear, sampleSpawnPos(env, rnd))
+		}
+	case arrivalBatch:
+		if rnd.Float64() < env.AppearProb*scale {
+			p := sampleSpawnPos(env, rnd)
+			n := arrival.BatchMin + rnd.Intn(arrival.BatchMax-arrival.BatchMin+1)
+			for i := 0; i < n; i++ {
+				appear = append(appear, p)
+			}
+		}
+	default:
+		//与えられた確率で新しいアイテムを1つ出現させる
+		if rnd.Float64() < env.AppearProb*scale {
+			appear = append(appear, sampleSpawnPos(env, rnd))
+		}
+	}
+	return appear, burst
+}
+
+//demandScale 時間帯ごとの需要の倍率を返す
+func demandScale(turn int, env *env.Env) float64 {
+	curve := env.Arrival.Curve
+	if len(curve) == 0 {
+		return 1
+	}
+	period := env.Arrival.CurvePeriod
+	return curve[(turn%period)*len(curve)/period]
+}
+
+//samplePoisson 平均lambdaのポアソン分布に従う乱数を返す
+func samplePoisson(lambda float64, rnd *rand.Rand) int {
+	if lambda <= 0 {
+		return 0
+	}
+	l := math.Exp(-lambda)
+	k := 0
+	p := rnd.Float64()
+	for p > l {
+		k++
+		p *= rnd.Float64()
+	}
+	return k
+}
+
+//sampleSpawnPos 出現の重みに従ってアイテムが出現する座標を選ぶ
+func sampleSpawnPos(env *env.Env, rnd *rand.Rand) pos.Pos {
+	if env.CumWeights == nil {
+		return env.SpawnPos[rnd.Intn(len(env.SpawnPos))]
+	}
+	total := env.CumWeights[len(env.CumWeights)-1]
+	r := rnd.Intn(total)
+	idx := sort.Search(len(env.CumWeights), func(i int) bool { return env.CumWeights[i] > r })
+	return env.SpawnPos[idx]
+}
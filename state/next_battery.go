@@ -0,0 +1,56 @@
+package state
+
+import (
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/pos"
+)
+
+//applyBattery 電池が切れたエージェントの行動をSTAYに置き換えた行動のスライスを返す
+func applyBattery(state *State, actions []int, env *env.Env) []int {
+	if !env.UsesBattery() {
+		return actions
+	}
+	ret := make([]int, len(actions))
+	copy(ret, actions)
+	for id, b := range state.AgentBattery {
+		if b == 0 {
+			ret[id] = action.STAY
+		}
+	}
+	return ret
+}
+
+//nextBattery 現在の状態, 各エージェントの行動, 次の状態のAgentItems, AgentPos, 環境設定を受け取り
+//次の状態のAgentBatteryを返す（移動とアイテムを拾う/回収することに成功したときに消費し, 充電ステーションでSTAYすると充電される）
+func nextBattery(state *State, actions []int, agentItems [][]item.Item, nxtPos []pos.Pos, env *env.Env) []int {
+	if !env.UsesBattery() {
+		return nil
+	}
+	battery := make([]int, env.NumAgents)
+	for id, b := range state.AgentBattery {
+		switch actions[id] {
+		case action.UP, action.DOWN, action.LEFT, action.RIGHT:
+			if nxtPos[id] != state.AgentPos[id] {
+				b -= env.Battery.MoveCost
+			}
+		case action.PICKUP, action.CLEAR:
+			if len(agentItems[id]) != len(state.AgentItems[id]) {
+				b -= env.Battery.ActionCost
+			}
+		case action.STAY:
+			if env.IsCharger(state.AgentPos[id]) {
+				b += env.Battery.ChargeRate
+			}
+		}
+		if b < 0 {
+			b = 0
+		}
+		if b > env.Battery.Capacity {
+			b = env.Battery.Capacity
+		}
+		battery[id] = b
+	}
+	return battery
+}
@@ -3,15 +3,16 @@ package state
 import (
 	"github.com/Div9851/warehouse-sim/action"
 	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
 	"github.com/Div9851/warehouse-sim/pos"
 )
 
 //nextItems 現在の状態, 各エージェントの行動, 環境設定を受け取り
 //次の状態のAgentItems, PosItems, Success, 各エージェントが得た報酬を返す
-func nextItems(state *State, actions []int, env *env.Env) ([]int, map[pos.Pos]int, []bool, []float64) {
-	agentItems := make([]int, env.NumAgents)
+func nextItems(state *State, actions []int, env *env.Env) ([][]item.Item, map[pos.Pos][]item.Item, []bool, []float64) {
+	agentItems := make([][]item.Item, env.NumAgents)
 	copy(agentItems, state.AgentItems)
-	posItems := make(map[pos.Pos]int)
+	posItems := make(map[pos.Pos][]item.Item)
 	for k, v := range state.PosItems {
 		posItems[k] = v
 	}
@@ -22,27 +23,43 @@ func nextItems(state *State, actions []int, env *env.Env) ([]int, map[pos.Pos]in
 		switch actions[i] {
 		case action.PICKUP:
 			//まだアイテムを拾うことが出来, かつそこにアイテムがあるなら
-			if agentItems[i] < env.MaxItems && posItems[pos] > 0 {
+			if len(agentItems[i]) < env.Capacity(i) && len(posItems[pos]) > 0 {
 				for id := 0; id < env.NumAgents; id++ {
 					rewards[id] += env.Reward
 				}
 				success[i] = true
 				rewards[i] += env.DIYBonus
-				agentItems[i]++
-				posItems[pos]--
-				if posItems[pos] == 0 {
+				//先に置かれたアイテムから拾う
+				agentItems[i] = appendItem(agentItems[i], posItems[pos][0])
+				posItems[pos] = posItems[pos][1:]
+				if len(posItems[pos]) == 0 {
 					delete(posItems, pos)
 				}
 			}
 		case action.CLEAR:
-			//デポにいて, かつアイテムをもっているなら
-			if pos == env.DepotPos && agentItems[i] > 0 {
+			//デポにいて, かつそのデポが受け付けるアイテムをもっているなら
+			cleared := env.NumAccepted(pos, agentItems[i])
+			if cleared > 0 {
+				//期限を過ぎたアイテムの数
+				late := 0
+				for _, it := range agentItems[i] {
+					if env.Accepts(pos, it.Type) && it.IsLate(state.Turn+1) {
+						late++
+					}
+				}
 				for id := 0; id < env.NumAgents; id++ {
-					rewards[id] += env.Reward * float64(agentItems[i])
+					rewards[id] += env.Reward*float64(cleared) - env.LatePenalty*float64(late)
 				}
 				success[i] = true
-				rewards[i] += env.DIYBonus * float64(agentItems[i])
-				agentItems[i] = 0
+				rewards[i] += env.DIYBonus * float64(cleared)
+				//受け付けられなかったアイテムは持ち続ける
+				rest := make([]item.Item, 0, len(agentItems[i])-cleared)
+				for _, it := range agentItems[i] {
+					if !env.Accepts(pos, it.Type) {
+						rest = append(rest, it)
+					}
+				}
+				agentItems[i] = rest
 			}
 		}
 	}
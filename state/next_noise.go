@@ -0,0 +1,53 @@
+package state
+
+import (
+	"math/rand"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+)
+
+//perpendicular 各移動の向きと垂直な向きの移動
+var perpendicular = map[int][]int{
+	action.UP:    {action.LEFT, action.RIGHT},
+	action.DOWN:  {action.LEFT, action.RIGHT},
+	action.LEFT:  {action.UP, action.DOWN},
+	action.RIGHT: {action.UP, action.DOWN},
+}
+
+//applyNoise 現在の状態, 各エージェントの行動, 環境設定, 乱数生成器を受け取り
+//失敗や故障を反映して実際に実行される行動のスライスと, 次の状態のAgentRepairを返す
+//（故障中のエージェントはSTAYし, このターンに故障したエージェントは次のターンから動けなくなる）
+func applyNoise(state *State, actions []int, env *env.Env, rnd *rand.Rand) ([]int, []int) {
+	if !env.UsesNoise() {
+		return actions, nil
+	}
+	ret := make([]int, len(actions))
+	copy(ret, actions)
+	var repair []int
+	if env.UsesBreakdown() {
+		repair = make([]int, env.NumAgents)
+	}
+	noise := env.Noise
+	for id := range ret {
+		if state.AgentRepair != nil && state.AgentRepair[id] > 0 {
+			ret[id] = action.STAY
+			repair[id] = state.AgentRepair[id] - 1
+			continue
+		}
+		switch ret[id] {
+		case action.UP, action.DOWN, action.LEFT, action.RIGHT:
+			if noise.SlipProb > 0 && rnd.Float64() < noise.SlipProb {
+				ret[id] = perpendicular[ret[id]][rnd.Intn(2)]
+			}
+		case action.PICKUP:
+			if noise.PickupFailProb > 0 && rnd.Float64() < noise.PickupFailProb {
+				ret[id] = action.STAY
+			}
+		}
+		if noise.BreakdownProb > 0 && rnd.Float64() < noise.BreakdownProb {
+			repair[id] = noise.RepairMin + rnd.Intn(noise.RepairMax-noise.RepairMin+1)
+		}
+	}
+	return ret, repair
+}
@@ -24,6 +24,10 @@ func nextPosOpt(state *State, actions []int, env *env.Env, rnd *rand.Rand, plann
 	for id, now := range state.AgentPos {
 		currentID[now] = id
 		nxtPos[id] = pos.NextPos(now, actions[id], env.MapData)
+		//移動できないターンや進入できないマスへの移動はその場にとどまる
+		if nxtPos[id] != now && (!env.CanMove(id, state.Turn) || !env.CanEnter(id, nxtPos[id])) {
+			nxtPos[id] = now
+		}
 		if nxtPos[id] != now {
 			nextID[nxtPos[id]] = append(nextID[nxtPos[id]], id)
 		}
@@ -4,28 +4,86 @@ import (
 	"math/rand"
 
 	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
 	"github.com/Div9851/warehouse-sim/pos"
 )
 
 //NextState 現在の状態, 各エージェントの行動, 環境設定, 乱数生成器を受け取り
 //次の状態, エージェントが取った行動, アイテムが出現した場所, 各エージェントが得た報酬を返す
-func NextState(state *State, actions []int, env *env.Env, rnd *rand.Rand) (*State, []int, *pos.Pos, []float64) {
+//（注文の履歴があってもそれは使わず, 到着過程の設定に従ってアイテムを出現させる）
+func NextState(state *State, actions []int, env *env.Env, rnd *rand.Rand) (*State, []int, []pos.Pos, []float64) {
 	return NextStateOpt(state, actions, env, rnd, -1, 0.0)
 }
 
+//ReplayState 注文の履歴があればそれに従ってアイテムを出現させるNextState
+//（履歴を再生するのは実際のシミュレーションだけで, MCTSなどの計画ではNextStateを使って将来の注文を知らないようにする）
+func ReplayState(state *State, actions []int, env *env.Env, rnd *rand.Rand) (*State, []int, []pos.Pos, []float64) {
+	return nextState(state, actions, env, rnd, -1, 0.0, true)
+}
+
 //NextStateOpt あるエージェントを優先するようなNextState
-func NextStateOpt(state *State, actions []int, env *env.Env, rnd *rand.Rand, plannerID int, opt float64) (*State, []int, *pos.Pos, []float64) {
+func NextStateOpt(state *State, actions []int, env *env.Env, rnd *rand.Rand, plannerID int, opt float64) (*State, []int, []pos.Pos, []float64) {
+	return nextState(state, actions, env, rnd, plannerID, opt, false)
+}
+
+//nextState NextStateOptの本体（replayが真なら注文の履歴を再生する）
+func nextState(state *State, actions []int, env *env.Env, rnd *rand.Rand, plannerID int, opt float64, replay bool) (*State, []int, []pos.Pos, []float64) {
+	//電池が切れたエージェントは動けない
+	actions = applyBattery(state, actions, env)
+	//移動で滑る, 拾うのに失敗する, 故障して動けなくなる
+	actions, repair := applyNoise(state, actions, env, rnd)
 	agentItems, posItems, successItems, rewards := nextItems(state, actions, env)
 	nxtPos, successPos := nextPosOpt(state, actions, env, rnd, plannerID, opt)
+	battery := nextBattery(state, actions, agentItems, nxtPos, env)
 	success := make([]bool, env.NumAgents)
 	for i := 0; i < env.NumAgents; i++ {
 		success[i] = successItems[i] || successPos[i]
 	}
-	var lastAppear *pos.Pos
-	//与えられた確率で新しいアイテムを出現させる
-	if rnd.Float64() < env.AppearProb {
-		lastAppear = &env.AllPos[rnd.Intn(len(env.AllPos))]
-		posItems[*lastAppear]++
+	turn := state.Turn + 1
+	//拾われないまま寿命を迎えたアイテムを消滅させる
+	expired := expireItems(posItems, turn, env)
+	for id := 0; id < env.NumAgents; id++ {
+		rewards[id] -= env.ExpiryPenalty * float64(len(expired))
+	}
+	//到着過程に従って新しいアイテムを出現させる
+	lastAppear, burst := nextArrivals(state, env, rnd, replay)
+	numSpawned := state.NumSpawned
+	for _, p := range lastAppear {
+		var itemType int
+		if env.ItemTypes > 1 {
+			itemType = rnd.Intn(env.ItemTypes)
+		}
+		var deadline int
+		if env.ItemDeadline > 0 {
+			deadline = turn + env.ItemDeadline
+		}
+		posItems[p] = appendItem(posItems[p], item.New(numSpawned, itemType, turn, deadline))
+		numSpawned++
+	}
+	return &State{Turn: turn, AgentItems: agentItems, AgentPos: nxtPos, PosItems: posItems, RandomValues: state.RandomValues, Success: success, Burst: burst, Expired: expired, NumSpawned: numSpawned, AgentBattery: battery, AgentRepair: repair}, actions, lastAppear, rewards
+}
+
+//expireItems あるターンに寿命を迎えたアイテムをposItemsから取り除き, 取り除いたアイテムのスライスを返す
+func expireItems(posItems map[pos.Pos][]item.Item, turn int, env *env.Env) []item.Item {
+	if env.ItemLifetime <= 0 {
+		return nil
+	}
+	var expired []item.Item
+	for p, items := range posItems {
+		//先に置かれたアイテムほど先頭にあるので, 先頭から寿命を迎えたものを取り除けばよい
+		k := 0
+		for k < len(items) && turn-items[k].SpawnTurn >= env.ItemLifetime {
+			k++
+		}
+		if k == 0 {
+			continue
+		}
+		expired = append(expired, items[:k]...)
+		if k == len(items) {
+			delete(posItems, p)
+		} else {
+			posItems[p] = items[k:]
+		}
 	}
-	return &State{Turn: state.Turn + 1, AgentItems: agentItems, AgentPos: nxtPos, PosItems: posItems, RandomValues: state.RandomValues, Success: success}, actions, lastAppear, rewards
+	return expired
 }
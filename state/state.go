@@ -1,21 +1,27 @@
 package state
 
 import (
+	"github.com/Div9851/warehouse-sim/item"
 	"github.com/Div9851/warehouse-sim/pos"
 )
 
 //State 状態を表す構造体
 type State struct {
 	Turn         int
-	AgentItems   []int
+	AgentItems   [][]item.Item
 	AgentPos     []pos.Pos
-	PosItems     map[pos.Pos]int
+	PosItems     map[pos.Pos][]item.Item
 	RandomValues map[pos.Pos]float64 //PosItemsのキーの順序を固定する
 	Success      []bool              //行動を実行できた場合に真
+	Burst        int                 //アイテムの到着のバーストの残りターン数
+	Expired      []item.Item         //直前のターンに寿命を迎えて消滅したアイテム
+	NumSpawned   int                 //これまでに出現したアイテムの数（次に出現するアイテムの番号）
+	AgentBattery []int               //各エージェントの電池の残量（電池の設定が無効ならnil）
+	AgentRepair  []int               //各エージェントが故障して動けない残りターン数（故障の設定が無効ならnil）
 }
 
 //New 新しいStateへのポインタを返す
-func New(turn int, agentItems []int, agentPos []pos.Pos, posItems map[pos.Pos]int, randomValues map[pos.Pos]float64, success []bool) *State {
+func New(turn int, agentItems [][]item.Item, agentPos []pos.Pos, posItems map[pos.Pos][]item.Item, randomValues map[pos.Pos]float64, success []bool) *State {
 	return &State{
 		Turn:         turn,
 		AgentItems:   agentItems,
@@ -25,3 +31,11 @@ func New(turn int, agentItems []int, agentPos []pos.Pos, posItems map[pos.Pos]in
 		Success:      success,
 	}
 }
+
+//appendItem アイテムのスライスの末尾にアイテムを追加した新しいスライスを返す
+//（元のスライスは他の状態と共有されている可能性があるので書き換えない）
+func appendItem(items []item.Item, it item.Item) []item.Item {
+	ret := make([]item.Item, len(items), len(items)+1)
+	copy(ret, items)
+	return append(ret, it)
+}
@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/sim"
+)
+
+//keyActions キーから行動への対応（矢印キー, WASD, HJKLで移動する）
+var keyActions = map[string]int{
+	"UP": action.UP, "w": action.UP, "k": action.UP,
+	"DOWN": action.DOWN, "s": action.DOWN, "j": action.DOWN,
+	"LEFT": action.LEFT, "a": action.LEFT, "h": action.LEFT,
+	"RIGHT": action.RIGHT, "d": action.RIGHT, "l": action.RIGHT,
+	"p": action.PICKUP,
+	"c": action.CLEAR,
+	" ": action.STAY, ".": action.STAY,
+}
+
+//actionNames 行動の名前
+var actionNames = []string{"UP", "DOWN", "LEFT", "RIGHT", "PICKUP", "CLEAR", "STAY"}
+
+//help 操作の説明
+const help = "arrows/wasd/hjkl: move  p: pickup  c: clear  space: stay  u: undo  q: quit"
+
+//Run 端末でHUMANのエージェントをキーボードで操作しながらシミュレーションを進める（qか入力の終わりで終了する）
+//（HUMANのエージェントが複数いれば番号の順に行動を選び, 全員が選んだらNextで1ターン進める. HUMANのエージェントがいなければ, 行動のキーで1ターン進める）
+func Run(s *sim.Simulator, in io.Reader, out io.Writer) error {
+	humans := []int{}
+	for id, algo := range s.Env.Algorithms {
+		if algo == "HUMAN" {
+			humans = append(humans, id)
+		}
+	}
+	r := bufio.NewReader(in)
+	chosen := make([]int, s.Env.NumAgents)
+	k := 0 //次に行動を選ぶHUMANのエージェントの添字
+	msg := ""
+	for {
+		render(out, s, humans, chosen, k, msg)
+		msg = ""
+		key, err := readKey(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "q":
+			return nil
+		case "u":
+			if s.Undo() {
+				k = 0
+				msg = "undone"
+			} else {
+				msg = "nothing to undo"
+			}
+			continue
+		}
+		act, ok := keyActions[key]
+		if !ok {
+			msg = fmt.Sprintf("unknown key %q", key)
+			continue
+		}
+		if s.State.Turn == s.Env.LastTurn {
+			msg = "simulation is over"
+			continue
+		}
+		if k < len(humans) {
+			chosen[humans[k]] = act
+			k++
+		}
+		if k == len(humans) {
+			s.HumanActions = append([]int{}, chosen...)
+			s.Next()
+			k = 0
+		}
+	}
+}
+
+//render 画面を消して現在の状態と操作の説明を描く
+func render(out io.Writer, s *sim.Simulator, humans []int, chosen []int, k int, msg string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(s.DumpState())
+	b.WriteString("\n\n")
+	switch {
+	case s.State.Turn == s.Env.LastTurn:
+		b.WriteString("[FINISHED]\n")
+	case k < len(humans):
+		id := humans[k]
+		p := s.State.AgentPos[id]
+		fmt.Fprintf(&b, "[HUMAN] choose action of agent %v at (%v, %v) carrying %v", id, p.X, p.Y, len(s.State.AgentItems[id]))
+		for _, other := range humans[:k] {
+			fmt.Fprintf(&b, " (agent %v: %v)", other, actionNames[chosen[other]])
+		}
+		b.WriteString("\n")
+	default:
+		b.WriteString("[AUTO] press an action key to advance one turn\n")
+	}
+	if s.CanUndo() {
+		b.WriteString("(undo available)\n")
+	}
+	b.WriteString(help + "\n")
+	if msg != "" {
+		b.WriteString(msg + "\n")
+	}
+	//端末を非カノニカルモードにしていても行頭に戻るようにする
+	fmt.Fprint(out, strings.ReplaceAll(b.String(), "\n", "\r\n"))
+}
+
+//readKey 1つのキーを読む（矢印キーのエスケープシーケンスはUP, DOWN, LEFT, RIGHTにする）
+func readKey(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if c != 0x1b {
+		return string(c), nil
+	}
+	seq := make([]byte, 2)
+	if _, err := io.ReadFull(r, seq); err != nil {
+		return "", err
+	}
+	if seq[0] == '[' {
+		switch seq[1] {
+		case 'A':
+			return "UP", nil
+		case 'B':
+			return "DOWN", nil
+		case 'C':
+			return "RIGHT", nil
+		case 'D':
+			return "LEFT", nil
+		}
+	}
+	return "ESC" + string(seq), nil
+}
@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Div9851/warehouse-sim/action"
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/sim"
+)
+
+func TestRun(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Algorithms = []string{"HUMAN", "GREEDY", "HUMAN"}
+	s, err := sim.New(e, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.KeepHistory = 10
+	start := s.State
+	var out bytes.Buffer
+	//1ターン目: エージェント0は右, エージェント2はとどまる. 戻してから両方とどまり, 不明なキーを押して終了する
+	if err := Run(s, strings.NewReader("\x1b[C u. .xq"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if s.State.Turn != 2 || s.CanUndo() != true {
+		t.Fatalf("simulation should be at turn 2 after undo and redo, but turn %v", s.State.Turn)
+	}
+	if s.LastActions[0] != action.STAY || s.LastActions[2] != action.STAY {
+		t.Fatalf("human agents should stay, but `%v`", s.LastActions)
+	}
+	if s.State.AgentPos[0] != start.AgentPos[0] {
+		t.Fatalf("agent 0 should be back at `%v`, but `%v`", start.AgentPos[0], s.State.AgentPos[0])
+	}
+	if !strings.Contains(out.String(), "undone") || !strings.Contains(out.String(), `unknown key "x"`) {
+		t.Fatalf("screen should report undo and the unknown key, but `%v`", out.String())
+	}
+	if !s.Undo() || s.State != start || s.CanUndo() {
+		t.Fatal("undo should return to the initial state")
+	}
+}
@@ -0,0 +1,240 @@
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/sim"
+	"github.com/Div9851/warehouse-sim/wire"
+)
+
+//go:embed static
+var static embed.FS
+
+//Spec ページが最初に読み込む環境の仕様
+type Spec struct {
+	MapData    []string  `json:"map_data"`
+	Depots     []pos.Pos `json:"depots"`
+	NumAgents  int       `json:"num_agents"`
+	LastTurn   int       `json:"last_turn"`
+	Algorithms []string  `json:"algorithms"`
+}
+
+//Frame 1ターンごとにページに送る状態
+type Frame struct {
+	Seed         int64             `json:"seed"`
+	Paused       bool              `json:"paused"`
+	DelayMS      int               `json:"delay_ms"`
+	Done         bool              `json:"done"`
+	Observation  *wire.Observation `json:"observation"`
+	LastActions  []int             `json:"last_actions"`
+	LastRewards  []float64         `json:"last_rewards"`
+	TotalRewards []float64         `json:"total_rewards"`
+	Pickups      int               `json:"pickups"` //拾われたアイテムの数の合計
+	Clears       int               `json:"clears"`  //デポに置かれた回数の合計
+}
+
+//Control ページからの操作
+type Control struct {
+	Cmd     string `json:"cmd"`      //pause, resume, step, speed, restart
+	DelayMS int    `json:"delay_ms"` //speedで設定する1ターンあたりの待ち時間（ミリ秒）
+	Seed    *int64 `json:"seed"`     //restartで使うシード値（省略すれば前のシード値+1）
+}
+
+//request runに渡す操作と, 操作の結果を返すチャネル
+type request struct {
+	Control
+	err chan error
+}
+
+//Server シミュレーションを実行し, 各ターンの状態をServer-Sent Eventsで配信するサーバー
+type Server struct {
+	env      *env.Env
+	controls chan request
+	mu       sync.Mutex
+	clients  map[chan []byte]bool
+	latest   []byte //最後に配信したフレーム（新しく接続したページにすぐ送る）
+}
+
+//NewServer 環境設定, 最初のシード値, 1ターンあたりの待ち時間を受け取り, シミュレーションを始めたサーバーを返す
+//（シミュレータを作れなければエラーを返す）
+func NewServer(env *env.Env, seed int64, delay time.Duration) (*Server, error) {
+	current, err := sim.New(env, seed)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{env: env, controls: make(chan request), clients: make(map[chan []byte]bool)}
+	go s.run(current, delay)
+	return s, nil
+}
+
+//Handler ページ, 仕様, イベント, 操作を返すハンドラを返す
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	root, _ := fs.Sub(static, "static")
+	mux.Handle("/", http.FileServer(http.FS(root)))
+	mux.HandleFunc("/api/spec", s.handleSpec)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	mux.HandleFunc("/api/control", s.handleControl)
+	return mux
+}
+
+//run シミュレーションを進め, 操作を受け付ける
+func (s *Server) run(current *sim.Simulator, delay time.Duration) {
+	seed := current.Seed
+	paused := false
+	s.publish(current, paused, delay)
+	for {
+		var tick <-chan time.Time
+		done := current.State.Turn == s.env.LastTurn
+		if !paused && !done {
+			tick = time.After(delay)
+		}
+		select {
+		case <-tick:
+			current.Next()
+		case c := <-s.controls:
+			var err error
+			switch c.Cmd {
+			case "pause":
+				paused = true
+			case "resume":
+				paused = false
+			case "step":
+				paused = true
+				current.Next()
+			case "speed":
+				delay = time.Duration(c.DelayMS) * time.Millisecond
+			case "restart":
+				nextSeed := seed + 1
+				if c.Seed != nil {
+					nextSeed = *c.Seed
+				}
+				//作れなければ今のシミュレーションを続ける
+				var next *sim.Simulator
+				next, err = sim.New(s.env, nextSeed)
+				if err != nil {
+					break
+				}
+				//前のシミュレータが起動した外部のコントローラを残さないように終了させる
+				current.Close()
+				current, seed = next, nextSeed
+			}
+			c.err <- err
+		}
+		s.publish(current, paused, delay)
+	}
+}
+
+//publish シミュレータの現在の状態をフレームにして全てのページに送る（受け取りが遅れているページには送らない）
+func (s *Server) publish(current *sim.Simulator, paused bool, delay time.Duration) {
+	frame := Frame{
+		Seed:         current.Seed,
+		Paused:       paused,
+		DelayMS:      int(delay / time.Millisecond),
+		Done:         current.State.Turn == s.env.LastTurn,
+		Observation:  wire.NewObservation(current.State, s.env),
+		LastActions:  current.LastActions,
+		LastRewards:  current.LastRewards,
+		TotalRewards: current.TotalRewards,
+	}
+	for id := range current.PickupCounts {
+		frame.Pickups += current.PickupCounts[id]
+		frame.Clears += current.ClearCounts[id]
+	}
+	b, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest = b
+	for ch := range s.clients {
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+}
+
+//handleSpec 環境の仕様を返す
+func (s *Server) handleSpec(w http.ResponseWriter, r *http.Request) {
+	depots := make([]pos.Pos, len(s.env.Depots))
+	for i, depot := range s.env.Depots {
+		depots[i] = depot.Pos
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Spec{MapData: s.env.MapData, Depots: depots, NumAgents: s.env.NumAgents, LastTurn: s.env.LastTurn, Algorithms: s.env.Algorithms})
+}
+
+//handleEvents 接続が切れるまでフレームをServer-Sent Eventsで送り続ける
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	ch := make(chan []byte, 16)
+	s.mu.Lock()
+	s.clients[ch] = true
+	if s.latest != nil {
+		ch <- s.latest
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+	for {
+		select {
+		case b := <-ch:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+//handleControl ページからの操作を受け付ける
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	var c Control
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, fmt.Sprintf("can't decode control (%s)", err), http.StatusBadRequest)
+		return
+	}
+	switch c.Cmd {
+	case "pause", "resume", "step", "restart":
+	case "speed":
+		if c.DelayMS < 0 {
+			http.Error(w, "delay_ms must not be negative", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, fmt.Sprintf("unknown cmd `%s`", c.Cmd), http.StatusBadRequest)
+		return
+	}
+	req := request{Control: c, err: make(chan error, 1)}
+	s.controls <- req
+	if err := <-req.err; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Div9851/warehouse-sim/env"
+)
+
+func TestServer(t *testing.T) {
+	e, err := env.Load("../env/testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Algorithms = []string{"GREEDY", "GREEDY", "GREEDY"}
+	//待ち時間を長くして, 操作したときだけ進むようにする
+	server, err := NewServer(e, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("page should be served, but %v `%v`", res.Status, res.Header.Get("Content-Type"))
+	}
+	events, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+	r := bufio.NewReader(events.Body)
+	next := func() Frame {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				var f Frame
+				if err := json.Unmarshal([]byte(line[len("data: "):]), &f); err != nil {
+					t.Fatal(err)
+				}
+				return f
+			}
+		}
+	}
+	control := func(body string) {
+		res, err := http.Post(ts.URL+"/api/control", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("control `%v` should be accepted, but %v", body, res.Status)
+		}
+	}
+	if f := next(); f.Observation.Turn != 1 || f.Seed != 1 || f.Paused {
+		t.Fatalf("first frame should be turn 1 of seed 1, but `%+v`", f)
+	}
+	control(`{"cmd":"step"}`)
+	if f := next(); f.Observation.Turn != 2 || !f.Paused || len(f.LastActions) != 3 {
+		t.Fatalf("step should pause at turn 2, but `%+v`", f)
+	}
+	control(`{"cmd":"restart","seed":7}`)
+	if f := next(); f.Observation.Turn != 1 || f.Seed != 7 {
+		t.Fatalf("restart should start seed 7 from turn 1, but `%+v`", f)
+	}
+	res, err = http.Post(ts.URL+"/api/control", "application/json", strings.NewReader(`{"cmd":"jump"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown control should be rejected, but %v", res.Status)
+	}
+}
@@ -0,0 +1,60 @@
+package wire
+
+import (
+	"sort"
+
+	"github.com/Div9851/warehouse-sim/env"
+	"github.com/Div9851/warehouse-sim/item"
+	"github.com/Div9851/warehouse-sim/learn"
+	"github.com/Div9851/warehouse-sim/pos"
+	"github.com/Div9851/warehouse-sim/state"
+)
+
+//FloorItems ある座標に置かれているアイテム
+type FloorItems struct {
+	Pos   pos.Pos     `json:"pos"`
+	Items []item.Item `json:"items"`
+}
+
+//Observation 観測（状態の全体と, 各エージェントから見た状態の特徴）
+type Observation struct {
+	Turn         int           `json:"turn"`
+	AgentPos     []pos.Pos     `json:"agent_pos"`
+	AgentItems   [][]item.Item `json:"agent_items"`
+	AgentBattery []int         `json:"agent_battery,omitempty"`
+	AgentRepair  []int         `json:"agent_repair,omitempty"`
+	Items        []FloorItems  `json:"items"`         //座標の順に並べた床のアイテム
+	Features     []learn.Key   `json:"features"`      //各エージェントから見た状態の特徴（learn.Encode）
+	ValidActions [][]int       `json:"valid_actions"` //各エージェントが選択できる行動
+}
+
+//NewObservation 状態から観測を返す
+func NewObservation(st *state.State, env *env.Env) *Observation {
+	obs := &Observation{
+		Turn:         st.Turn,
+		AgentPos:     st.AgentPos,
+		AgentItems:   make([][]item.Item, env.NumAgents),
+		AgentBattery: st.AgentBattery,
+		AgentRepair:  st.AgentRepair,
+		Items:        make([]FloorItems, 0, len(st.PosItems)),
+		Features:     make([]learn.Key, env.NumAgents),
+		ValidActions: make([][]int, env.NumAgents),
+	}
+	for p, items := range st.PosItems {
+		obs.Items = append(obs.Items, FloorItems{Pos: p, Items: items})
+	}
+	sort.Slice(obs.Items, func(i, j int) bool {
+		a, b := obs.Items[i].Pos, obs.Items[j].Pos
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.X < b.X
+	})
+	for id := 0; id < env.NumAgents; id++ {
+		//JSONでnullにならないように空のスライスにする
+		obs.AgentItems[id] = append([]item.Item{}, st.AgentItems[id]...)
+		obs.Features[id] = learn.Encode(id, st, env)
+		obs.ValidActions[id] = learn.ValidActions(id, st, env)
+	}
+	return obs
+}